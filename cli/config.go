@@ -0,0 +1,231 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/flashbots/mev-boost/common"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/urfave/cli/v3"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errConflictingPubkey     = errors.New("relay public key set both in url and pubkey field")
+	errNegativeBidMultiplier = errors.New("please specify a non-negative bid multiplier")
+	errLargeBidAdjustment    = errors.New("bid adjustment is too large, please ensure bid_adjustment is denominated in Ethers")
+)
+
+// configFile is the structure of the YAML file passed via --config.
+// Unset fields fall back to the corresponding cli flag, flags explicitly set on the command line take precedence.
+type configFile struct {
+	ListenAddr  *string `yaml:"listen_addr"`
+	MetricsAddr *string `yaml:"metrics_addr"`
+
+	LogJSON       *bool             `yaml:"log_json"`
+	LogLevel      *string           `yaml:"log_level"`
+	LogService    *string           `yaml:"log_service"`
+	LogNoVersion  *bool             `yaml:"log_no_version"`
+	Network       *string           `yaml:"network"`
+	NetworkConfig *string           `yaml:"network_config"`
+	GenesisFork   *string           `yaml:"genesis_fork_version"`
+	GenesisTime   *uint64           `yaml:"genesis_timestamp"`
+	ForkEpochs    map[string]uint64 `yaml:"fork_epochs"`
+	RelayCheck    *bool             `yaml:"relay_check"`
+	MinBid        *float64          `yaml:"min_bid"`
+	BidSelector   *string           `yaml:"bid_selector"`
+	RelayMonitors []string          `yaml:"relay_monitors"`
+
+	MaxBid         *float64 `yaml:"max_bid"`
+	ExtraDataAllow *string  `yaml:"extra_data_allow"`
+	ExtraDataDeny  *string  `yaml:"extra_data_deny"`
+
+	TimeoutGetHeaderMs   *int64 `yaml:"request_timeout_getheader_ms"`
+	TimeoutGetPayloadMs  *int64 `yaml:"request_timeout_getpayload_ms"`
+	TimeoutRegValMs      *int64 `yaml:"request_timeout_regval_ms"`
+	DeadlineGetHeaderMs  *int64 `yaml:"request_deadline_getheader_ms"`
+	CutoffGetHeaderMs    *int64 `yaml:"request_cutoff_getheader_ms"`
+	DeadlineGetPayloadMs *int64 `yaml:"request_deadline_getpayload_ms"`
+	RelayCheckIntervalMs *int64 `yaml:"relay_check_interval_ms"`
+	MaxRetries           *int64 `yaml:"request_max_retries"`
+
+	RelayBreakerThreshold  *int64 `yaml:"relay_breaker_threshold"`
+	RelayBreakerCooldownMs *int64 `yaml:"relay_breaker_cooldown_ms"`
+
+	VerifyBlobs            *bool   `yaml:"verify_blobs"`
+	PayloadFallbackRelays  *string `yaml:"payload_fallback_relays"`
+	BidStoreFile           *string `yaml:"bid_store_file"`
+	BidStoreRetentionSlots *int64  `yaml:"bid_store_retention_slots"`
+	SignedBlocksFile       *string `yaml:"signed_blocks_file"`
+
+	TimingGames               *bool  `yaml:"timing_games"`
+	TimingGamesDelayMs        *int64 `yaml:"timing_games_delay_ms"`
+	TimingGamesPollIntervalMs *int64 `yaml:"timing_games_poll_interval_ms"`
+
+	Server configServer  `yaml:"server"`
+	Relays []configRelay `yaml:"relays"`
+}
+
+// configServer holds the settings otherwise read from environment variables in the config package
+type configServer struct {
+	ReadTimeoutMs           *int    `yaml:"read_timeout_ms"`
+	ReadHeaderTimeoutMs     *int    `yaml:"read_header_timeout_ms"`
+	WriteTimeoutMs          *int    `yaml:"write_timeout_ms"`
+	IdleTimeoutMs           *int    `yaml:"idle_timeout_ms"`
+	MaxHeaderBytes          *int    `yaml:"max_header_bytes"`
+	SkipRelaySignatureCheck *bool   `yaml:"skip_relay_signature_check"`
+	SlotTimeSec             *uint64 `yaml:"slot_time_sec"`
+	BeaconNodeTimeoutMargin *int    `yaml:"beacon_node_timeout_margin_ms"`
+}
+
+// configRelay is a single relay entry in the config file
+type configRelay struct {
+	URL               string            `yaml:"url"`
+	Pubkey            string            `yaml:"pubkey"`
+	Name              string            `yaml:"name"`
+	Enabled           *bool             `yaml:"enabled"`
+	TimeoutGetHeader  int64             `yaml:"timeout_getheader_ms"`
+	TimeoutGetPayload int64             `yaml:"timeout_getpayload_ms"`
+	TimeoutRegVal     int64             `yaml:"timeout_regval_ms"`
+	MaxRetries        int               `yaml:"max_retries"`
+	MinBid            *float64          `yaml:"min_bid"`
+	BidMultiplier     float64           `yaml:"bid_multiplier"`
+	BidAdjustment     float64           `yaml:"bid_adjustment"`
+	Headers           map[string]string `yaml:"headers"`
+}
+
+// loadConfigFile reads and parses a YAML config file
+func loadConfigFile(path string) (*configFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := new(configFile)
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// relayURL returns the relay url in the scheme://pubkey@host form expected by types.NewRelayEntry
+func (r *configRelay) relayURL() (string, error) {
+	if r.Pubkey == "" {
+		return r.URL, nil
+	}
+	if strings.Contains(r.URL, "@") {
+		return "", errConflictingPubkey
+	}
+	if scheme, host, found := strings.Cut(r.URL, "://"); found {
+		return scheme + "://" + r.Pubkey + "@" + host, nil
+	}
+	return r.Pubkey + "@" + r.URL, nil
+}
+
+// relayEntry converts the config entry into a relay entry with its per-relay settings
+func (r *configRelay) relayEntry() (types.RelayEntry, error) {
+	relayURL, err := r.relayURL()
+	if err != nil {
+		return types.RelayEntry{}, err
+	}
+	entry, err := types.NewRelayEntry(relayURL)
+	if err != nil {
+		return entry, err
+	}
+
+	entry.Name = r.Name
+	entry.Headers = r.Headers
+	entry.TimeoutGetHeader = time.Duration(r.TimeoutGetHeader) * time.Millisecond
+	entry.TimeoutGetPayload = time.Duration(r.TimeoutGetPayload) * time.Millisecond
+	entry.TimeoutRegVal = time.Duration(r.TimeoutRegVal) * time.Millisecond
+	entry.MaxRetries = r.MaxRetries
+	if r.MinBid != nil {
+		entry.MinBid, err = sanitizeMinBid(*r.MinBid)
+		if err != nil {
+			return entry, err
+		}
+	}
+	if r.BidMultiplier < 0 {
+		return entry, errNegativeBidMultiplier
+	}
+	entry.BidMultiplier = r.BidMultiplier
+	if r.BidAdjustment != 0 {
+		entry.BidAdjustment, err = sanitizeBidAdjustment(r.BidAdjustment)
+		if err != nil {
+			return entry, err
+		}
+	}
+	return entry, nil
+}
+
+// sanitizeBidAdjustment converts a bid adjustment in eth, which may be negative, to wei
+func sanitizeBidAdjustment(adjustment float64) (*big.Int, error) {
+	if math.Abs(adjustment) > 1000000.0 {
+		return nil, errLargeBidAdjustment
+	}
+	wei, err := common.FloatEthTo256Wei(math.Abs(adjustment))
+	if err != nil {
+		return nil, err
+	}
+	value := wei.BigInt()
+	if adjustment < 0 {
+		value.Neg(value)
+	}
+	return value, nil
+}
+
+// relayEntries returns the enabled relays of the config file
+func (c *configFile) relayEntries() ([]types.RelayEntry, error) {
+	entries := make([]types.RelayEntry, 0, len(c.Relays))
+	for _, relay := range c.Relays {
+		if relay.Enabled != nil && !*relay.Enabled {
+			continue
+		}
+		entry, err := relay.relayEntry()
+		if err != nil {
+			return nil, fmt.Errorf("invalid relay %s: %w", relay.URL, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// applyServerSettings overrides the config package defaults, unless the corresponding environment variable is set
+func (c *configFile) applyServerSettings() {
+	setInt := func(dst *int, value *int, envKey string) {
+		if _, ok := os.LookupEnv(envKey); value != nil && !ok {
+			*dst = *value
+		}
+	}
+	setInt(&config.ServerReadTimeoutMs, c.Server.ReadTimeoutMs, "MEV_BOOST_SERVER_READ_TIMEOUT_MS")
+	setInt(&config.ServerReadHeaderTimeoutMs, c.Server.ReadHeaderTimeoutMs, "MEV_BOOST_SERVER_READ_HEADER_TIMEOUT_MS")
+	setInt(&config.ServerWriteTimeoutMs, c.Server.WriteTimeoutMs, "MEV_BOOST_SERVER_WRITE_TIMEOUT_MS")
+	setInt(&config.ServerIdleTimeoutMs, c.Server.IdleTimeoutMs, "MEV_BOOST_SERVER_IDLE_TIMEOUT_MS")
+	setInt(&config.ServerMaxHeaderBytes, c.Server.MaxHeaderBytes, "MAX_HEADER_BYTES")
+	setInt(&config.BeaconNodeTimeoutMarginMs, c.Server.BeaconNodeTimeoutMargin, "BEACON_NODE_TIMEOUT_MARGIN_MS")
+
+	if _, ok := os.LookupEnv("SKIP_RELAY_SIGNATURE_CHECK"); c.Server.SkipRelaySignatureCheck != nil && !ok {
+		config.SkipRelaySignatureCheck = *c.Server.SkipRelaySignatureCheck
+	}
+	if _, ok := os.LookupEnv("SLOT_SEC"); c.Server.SlotTimeSec != nil && !ok {
+		config.SlotTimeSec = *c.Server.SlotTimeSec
+	}
+}
+
+// option returns the value of a flag if set on the command line, else the config file value if present,
+// and the flag default otherwise
+func option[T any](cmd *cli.Command, name string, fileValue *T, get func(string) T) T {
+	if cmd.IsSet(name) || fileValue == nil {
+		return get(name)
+	}
+	return *fileValue
+}
@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flashbots/go-boost-utils/types"
+	"github.com/stretchr/testify/require"
+)
+
+const testRelayPubkey = "0x82f6e7cc57a2ce68ec41321bebc55bcb31945fe66a8e67eb8251425fab4c6a38c10c53210aea9796dd0ba0441b46762a"
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := writeConfigFile(t, `
+listen_addr: localhost:18551
+min_bid: 0.05
+request_timeout_getheader_ms: 900
+timing_games: true
+timing_games_poll_interval_ms: 50
+server:
+  max_header_bytes: 8000
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com
+    name: relay-a
+    timeout_getheader_ms: 500
+    timeout_getpayload_ms: 2000
+    max_retries: 2
+    min_bid: 0.1
+    bid_multiplier: 0.95
+    headers:
+      X-Api-Key: secret
+  - url: https://relay-b.example.com
+    pubkey: `+testRelayPubkey+`
+    bid_adjustment: -0.01
+  - url: https://`+testRelayPubkey+`@relay-c.example.com
+    enabled: false
+`)
+
+	cfg, err := loadConfigFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "localhost:18551", *cfg.ListenAddr)
+	require.InDelta(t, 0.05, *cfg.MinBid, 0)
+	require.Equal(t, int64(900), *cfg.TimeoutGetHeaderMs)
+	require.Equal(t, 8000, *cfg.Server.MaxHeaderBytes)
+	require.Nil(t, cfg.TimeoutGetPayloadMs)
+	require.True(t, *cfg.TimingGames)
+	require.Equal(t, int64(50), *cfg.TimingGamesPollIntervalMs)
+	require.Nil(t, cfg.TimingGamesDelayMs)
+
+	relays, err := cfg.relayEntries()
+	require.NoError(t, err)
+	require.Len(t, relays, 2)
+
+	require.Equal(t, "relay-a", relays[0].Name)
+	require.Equal(t, "relay-a", relays[0].DisplayName())
+	require.Equal(t, 500*time.Millisecond, relays[0].TimeoutGetHeader)
+	require.Equal(t, 2000*time.Millisecond, relays[0].TimeoutGetPayload)
+	require.Equal(t, time.Duration(0), relays[0].TimeoutRegVal)
+	require.Equal(t, 2, relays[0].MaxRetries)
+	require.Equal(t, map[string]string{"X-Api-Key": "secret"}, relays[0].Headers)
+	expectedMinBid := types.U256Str{}
+	require.NoError(t, expectedMinBid.UnmarshalText([]byte("100000000000000000")))
+	require.Equal(t, expectedMinBid, *relays[0].MinBid)
+	require.InDelta(t, 0.95, relays[0].BidMultiplier, 0)
+	require.Nil(t, relays[0].BidAdjustment)
+
+	require.Equal(t, "https://"+testRelayPubkey+"@relay-b.example.com", relays[1].String())
+	require.Equal(t, "relay-b.example.com", relays[1].DisplayName())
+	require.Nil(t, relays[1].MinBid)
+	require.Zero(t, relays[1].BidMultiplier)
+	require.Equal(t, big.NewInt(-10_000_000_000_000_000), relays[1].BidAdjustment)
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	t.Run("Unknown field", func(t *testing.T) {
+		path := writeConfigFile(t, "relayz: []\n")
+		_, err := loadConfigFile(path)
+		require.Error(t, err)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		_, err := loadConfigFile(filepath.Join(t.TempDir(), "missing.yaml"))
+		require.Error(t, err)
+	})
+
+	t.Run("Public key set twice", func(t *testing.T) {
+		path := writeConfigFile(t, `
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com
+    pubkey: `+testRelayPubkey+`
+`)
+		cfg, err := loadConfigFile(path)
+		require.NoError(t, err)
+		_, err = cfg.relayEntries()
+		require.ErrorIs(t, err, errConflictingPubkey)
+	})
+
+	t.Run("Negative relay min bid", func(t *testing.T) {
+		path := writeConfigFile(t, `
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com
+    min_bid: -1
+`)
+		cfg, err := loadConfigFile(path)
+		require.NoError(t, err)
+		_, err = cfg.relayEntries()
+		require.ErrorIs(t, err, errNegativeBid)
+	})
+
+	t.Run("Negative relay bid multiplier", func(t *testing.T) {
+		path := writeConfigFile(t, `
+relays:
+  - url: https://`+testRelayPubkey+`@relay-a.example.com
+    bid_multiplier: -1
+`)
+		cfg, err := loadConfigFile(path)
+		require.NoError(t, err)
+		_, err = cfg.relayEntries()
+		require.ErrorIs(t, err, errNegativeBidMultiplier)
+	})
+}
@@ -13,6 +13,8 @@ var flags = []cli.Flag{
 	// general
 	addrFlag,
 	versionFlag,
+	metricsAddrFlag,
+	configFlag,
 	// logging
 	jsonFlag,
 	debugFlag,
@@ -25,15 +27,35 @@ var flags = []cli.Flag{
 	mainnetFlag,
 	sepoliaFlag,
 	holeskyFlag,
+	networkConfigFlag,
+	forkEpochsFlag,
 	// relay
 	relaysFlag,
 	relayMonitorFlag,
 	minBidFlag,
+	maxBidFlag,
+	extraDataAllowFlag,
+	extraDataDenyFlag,
+	bidSelectorFlag,
 	relayCheckFlag,
+	relayCheckIntervalFlag,
+	relayBreakerThresholdFlag,
+	relayBreakerCooldownFlag,
 	timeoutGetHeaderFlag,
 	timeoutGetPayloadFlag,
 	timeoutRegValFlag,
+	deadlineGetHeaderFlag,
+	cutoffGetHeaderFlag,
+	deadlineGetPayloadFlag,
 	maxRetriesFlag,
+	verifyBlobsFlag,
+	payloadFallbackRelaysFlag,
+	bidStoreFileFlag,
+	bidStoreRetentionSlotsFlag,
+	signedBlocksFileFlag,
+	timingGamesFlag,
+	timingGamesDelayFlag,
+	timingGamesPollIntervalFlag,
 }
 
 var (
@@ -50,6 +72,18 @@ var (
 		Usage:    "print version",
 		Category: GeneralCategory,
 	}
+	configFlag = &cli.StringFlag{
+		Name:     "config",
+		Sources:  cli.EnvVars("CONFIG_FILE"),
+		Usage:    "path to a YAML config file with relays and global settings, flags set on the command line take precedence",
+		Category: GeneralCategory,
+	}
+	metricsAddrFlag = &cli.StringFlag{
+		Name:     "metrics-addr",
+		Sources:  cli.EnvVars("METRICS_ADDR"),
+		Usage:    "listen-address for the Prometheus metrics server, disabled if empty (eg. localhost:18551)",
+		Category: GeneralCategory,
+	}
 	// Logging and debugging
 	jsonFlag = &cli.BoolFlag{
 		Name:     "json",
@@ -115,6 +149,18 @@ var (
 		Usage:    "use Holesky",
 		Category: GenesisCategory,
 	}
+	networkConfigFlag = &cli.StringFlag{
+		Name:     "network-config",
+		Sources:  cli.EnvVars("NETWORK_CONFIG"),
+		Usage:    "path to a consensus-layer config.yaml, or a directory with config.yaml and optionally genesis.ssz, used for custom networks",
+		Category: GenesisCategory,
+	}
+	forkEpochsFlag = &cli.StringSliceFlag{
+		Name:     "fork-epochs",
+		Sources:  cli.EnvVars("FORK_EPOCHS"),
+		Usage:    "fork activation epochs, overriding the network's fork schedule (eg. deneb=0,electra=100)",
+		Category: GenesisCategory,
+	}
 	// Relay
 	relaysFlag = &cli.StringSliceFlag{
 		Name:     "relay",
@@ -136,12 +182,57 @@ var (
 		Usage:    "minimum bid to accept from a relay [eth]",
 		Category: RelayCategory,
 	}
+	maxBidFlag = &cli.FloatFlag{
+		Name:     "max-bid",
+		Sources:  cli.EnvVars("MAX_BID_ETH"),
+		Usage:    "maximum bid to accept from a relay, as a sanity cap, disabled if 0 [eth]",
+		Category: RelayCategory,
+	}
+	extraDataAllowFlag = &cli.StringFlag{
+		Name:     "extra-data-allow",
+		Sources:  cli.EnvVars("EXTRA_DATA_ALLOW"),
+		Usage:    "only accept bids whose block extra data (the builder tag) matches this regular expression",
+		Category: RelayCategory,
+	}
+	extraDataDenyFlag = &cli.StringFlag{
+		Name:     "extra-data-deny",
+		Sources:  cli.EnvVars("EXTRA_DATA_DENY"),
+		Usage:    "refuse bids whose block extra data (the builder tag) matches this regular expression",
+		Category: RelayCategory,
+	}
+	bidSelectorFlag = &cli.StringFlag{
+		Name:     "bid-selector",
+		Sources:  cli.EnvVars("BID_SELECTOR"),
+		Usage:    "how to select the bid among the relay bids: default (highest value), relay-priority (first relay in the list with a bid) or reliability-weighted (value weighted by the relay status checks)",
+		Value:    "default",
+		Category: RelayCategory,
+	}
 	relayCheckFlag = &cli.BoolFlag{
 		Name:     "relay-check",
 		Sources:  cli.EnvVars("RELAY_STARTUP_CHECK"),
 		Usage:    "check relay status on startup and on the status API call",
 		Category: RelayCategory,
 	}
+	relayCheckIntervalFlag = &cli.IntFlag{
+		Name:     "relay-check-interval",
+		Sources:  cli.EnvVars("RELAY_CHECK_INTERVAL_MS"),
+		Usage:    "interval between relay status checks in the background, with relay-check, relays checked on each status call if 0 [ms]",
+		Value:    12000,
+		Category: RelayCategory,
+	}
+	relayBreakerThresholdFlag = &cli.IntFlag{
+		Name:     "relay-breaker-threshold",
+		Sources:  cli.EnvVars("RELAY_BREAKER_THRESHOLD"),
+		Usage:    "consecutive getHeader or status failures after which a relay is skipped for getHeader, disabled if 0",
+		Category: RelayCategory,
+	}
+	relayBreakerCooldownFlag = &cli.IntFlag{
+		Name:     "relay-breaker-cooldown",
+		Sources:  cli.EnvVars("RELAY_BREAKER_COOLDOWN_MS"),
+		Usage:    "time after which a skipped relay is tried again for getHeader, unless a status check succeeds first [ms]",
+		Value:    60000,
+		Category: RelayCategory,
+	}
 	// mev-boost relay request timeouts (see also https://github.com/flashbots/mev-boost/issues/287)
 	timeoutGetHeaderFlag = &cli.IntFlag{
 		Name:     "request-timeout-getheader",
@@ -164,6 +255,25 @@ var (
 		Value:    3000,
 		Category: RelayCategory,
 	}
+	// deadlines relative to the slot start, capping the request timeouts
+	deadlineGetHeaderFlag = &cli.IntFlag{
+		Name:     "request-deadline-getheader",
+		Sources:  cli.EnvVars("RELAY_DEADLINE_MS_GETHEADER"),
+		Usage:    "stop waiting for bids this long after the slot start, disabled if 0 [ms]",
+		Category: RelayCategory,
+	}
+	cutoffGetHeaderFlag = &cli.IntFlag{
+		Name:     "request-cutoff-getheader",
+		Sources:  cli.EnvVars("RELAY_CUTOFF_MS_GETHEADER"),
+		Usage:    "return no bid to getHeader requests arriving this long after the slot start, disabled if 0 [ms]",
+		Category: RelayCategory,
+	}
+	deadlineGetPayloadFlag = &cli.IntFlag{
+		Name:     "request-deadline-getpayload",
+		Sources:  cli.EnvVars("RELAY_DEADLINE_MS_GETPAYLOAD"),
+		Usage:    "stop waiting for the payload this long after the slot start, disabled if 0 [ms]",
+		Category: RelayCategory,
+	}
 	maxRetriesFlag = &cli.IntFlag{
 		Name:     "request-max-retries",
 		Sources:  cli.EnvVars("REQUEST_MAX_RETRIES"),
@@ -171,4 +281,57 @@ var (
 		Value:    5,
 		Category: RelayCategory,
 	}
+	verifyBlobsFlag = &cli.BoolFlag{
+		Name:     "verify-blobs",
+		Sources:  cli.EnvVars("VERIFY_BLOBS"),
+		Usage:    "verify the KZG proofs of the blobs returned by relays for getPayload, and wait for another relay if invalid",
+		Category: RelayCategory,
+	}
+	payloadFallbackRelaysFlag = &cli.StringFlag{
+		Name:     "payload-fallback-relays",
+		Sources:  cli.EnvVars("PAYLOAD_FALLBACK_RELAYS"),
+		Usage:    "relays to send the signed blinded block to when no relay is known to have offered its bid: all or none",
+		Value:    "all",
+		Category: RelayCategory,
+	}
+	bidStoreFileFlag = &cli.StringFlag{
+		Name:     "bid-store-file",
+		Sources:  cli.EnvVars("BID_STORE_FILE"),
+		Usage:    "file to keep the bids returned for getHeader in, to send getPayload to the relays which offered the bid across restarts",
+		Category: RelayCategory,
+	}
+	bidStoreRetentionSlotsFlag = &cli.IntFlag{
+		Name:     "bid-store-retention-slots",
+		Sources:  cli.EnvVars("BID_STORE_RETENTION_SLOTS"),
+		Usage:    "number of slots for which bids are kept in the bid store file",
+		Value:    64,
+		Category: RelayCategory,
+	}
+	signedBlocksFileFlag = &cli.StringFlag{
+		Name:     "signed-blocks-file",
+		Sources:  cli.EnvVars("SIGNED_BLOCKS_FILE"),
+		Usage:    "file to save the blocks submitted for each slot to, to refuse a different block for the same slot across restarts",
+		Category: RelayCategory,
+	}
+	// timing games: keep polling the relays for getHeader until a deadline into the slot
+	timingGamesFlag = &cli.BoolFlag{
+		Name:     "timing-games",
+		Sources:  cli.EnvVars("TIMING_GAMES"),
+		Usage:    "keep polling relays for bids until the timing games delay into the slot, and return the best bid seen",
+		Category: RelayCategory,
+	}
+	timingGamesDelayFlag = &cli.IntFlag{
+		Name:     "timing-games-delay",
+		Sources:  cli.EnvVars("TIMING_GAMES_DELAY_MS"),
+		Usage:    "time from slot start until which relays are polled for bids, with timing games [ms]",
+		Value:    500,
+		Category: RelayCategory,
+	}
+	timingGamesPollIntervalFlag = &cli.IntFlag{
+		Name:     "timing-games-poll-interval",
+		Sources:  cli.EnvVars("TIMING_GAMES_POLL_INTERVAL_MS"),
+		Usage:    "interval between getHeader requests to the relays, with timing games [ms]",
+		Value:    100,
+		Category: RelayCategory,
+	}
 )
@@ -6,13 +6,16 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost/common"
 	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server"
+	serverTypes "github.com/flashbots/mev-boost/server/types"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v3"
 )
@@ -20,20 +23,20 @@ import (
 const (
 	genesisForkVersionMainnet = "0x00000000"
 	genesisForkVersionSepolia = "0x90000069"
-	genesisForkVersionGoerli  = "0x00001020"
 	genesisForkVersionHolesky = "0x01017000"
 
 	genesisTimeMainnet = 1606824023
 	genesisTimeSepolia = 1655733600
-	genesisTimeGoerli  = 1614588812
 	genesisTimeHolesky = 1695902400
 )
 
 var (
 	// errors
-	errInvalidLoglevel = errors.New("invalid loglevel")
-	errNegativeBid     = errors.New("please specify a non-negative minimum bid")
-	errLargeMinBid     = errors.New("minimum bid is too large, please ensure min-bid is denominated in Ethers")
+	errInvalidLoglevel  = errors.New("invalid loglevel")
+	errNegativeBid      = errors.New("please specify a non-negative minimum bid")
+	errLargeMinBid      = errors.New("minimum bid is too large, please ensure min-bid is denominated in Ethers")
+	errNegativeMaxBid   = errors.New("please specify a non-negative maximum bid")
+	errInvalidForkEpoch = errors.New("invalid fork epoch, expected fork=epoch")
 
 	log = logrus.NewEntry(logrus.New())
 )
@@ -52,79 +55,162 @@ func Main() {
 }
 
 // start starts the mev-boost cli
-func start(_ context.Context, cmd *cli.Command) error {
+func start(ctx context.Context, cmd *cli.Command) error {
 	// Only print the version if the flag is set
 	if cmd.IsSet(versionFlag.Name) {
 		log.Infof("mev-boost %s\n", config.Version)
 		return nil
 	}
 
-	if err := setupLogging(cmd); err != nil {
+	cfg := new(configFile)
+	if cmd.IsSet(configFlag.Name) {
+		var err error
+		cfg, err = loadConfigFile(cmd.String(configFlag.Name))
+		if err != nil {
+			log.WithError(err).Fatal("failed loading config file")
+		}
+		cfg.applyServerSettings()
+	}
+
+	if err := setupLogging(cmd, cfg); err != nil {
 		flag.Usage()
 		log.WithError(err).Fatal("failed setting up logging")
 	}
 
 	var (
-		genesisForkVersion, genesisTime      = setupGenesis(cmd)
-		relays, monitors, minBid, relayCheck = setupRelays(cmd)
-		listenAddr                           = cmd.String(addrFlag.Name)
+		genesisForkVersion, genesisTime, forkSchedule = setupGenesis(cmd, cfg)
+		relays, monitors, minBid, relayCheck          = setupRelays(cmd, cfg)
+		listenAddr                                    = option(cmd, addrFlag.Name, cfg.ListenAddr, cmd.String)
+		metricsAddr                                   = option(cmd, metricsAddrFlag.Name, cfg.MetricsAddr, cmd.String)
+		settings                                      = runtimeSettings(cmd, cfg, relays, minBid)
 	)
 
+	bidSelector, err := server.NewBidSelector(option(cmd, bidSelectorFlag.Name, cfg.BidSelector, cmd.String))
+	if err != nil {
+		log.WithError(err).Fatal("invalid bid selector")
+	}
+	bidFilters, err := setupBidFilters(cmd, cfg)
+	if err != nil {
+		log.WithError(err).Fatal("invalid bid filters")
+	}
+
 	opts := server.BoostServiceOpts{
 		Log:                      log,
 		ListenAddr:               listenAddr,
-		Relays:                   relays,
+		MetricsAddr:              metricsAddr,
+		Relays:                   settings.Relays,
 		RelayMonitors:            monitors,
 		GenesisForkVersionHex:    genesisForkVersion,
 		GenesisTime:              genesisTime,
+		ForkSchedule:             forkSchedule,
 		RelayCheck:               relayCheck,
-		RelayMinBid:              minBid,
-		RequestTimeoutGetHeader:  time.Duration(cmd.Int(timeoutGetHeaderFlag.Name)) * time.Millisecond,
-		RequestTimeoutGetPayload: time.Duration(cmd.Int(timeoutGetPayloadFlag.Name)) * time.Millisecond,
-		RequestTimeoutRegVal:     time.Duration(cmd.Int(timeoutRegValFlag.Name)) * time.Millisecond,
-		RequestMaxRetries:        int(cmd.Int(maxRetriesFlag.Name)),
+		RelayCheckInterval:       time.Duration(option(cmd, relayCheckIntervalFlag.Name, cfg.RelayCheckIntervalMs, cmd.Int)) * time.Millisecond,
+		RelayMinBid:              settings.RelayMinBid,
+		RelayBreakerThreshold:    int(option(cmd, relayBreakerThresholdFlag.Name, cfg.RelayBreakerThreshold, cmd.Int)),
+		RelayBreakerCooldown:     time.Duration(option(cmd, relayBreakerCooldownFlag.Name, cfg.RelayBreakerCooldownMs, cmd.Int)) * time.Millisecond,
+		BidSelector:              bidSelector,
+		BidFilters:               bidFilters,
+		VerifyBlobs:              option(cmd, verifyBlobsFlag.Name, cfg.VerifyBlobs, cmd.Bool),
+		PayloadFallbackRelays:    option(cmd, payloadFallbackRelaysFlag.Name, cfg.PayloadFallbackRelays, cmd.String),
+		BidStoreFile:             option(cmd, bidStoreFileFlag.Name, cfg.BidStoreFile, cmd.String),
+		BidStoreRetentionSlots:   uint64(max(option(cmd, bidStoreRetentionSlotsFlag.Name, cfg.BidStoreRetentionSlots, cmd.Int), 0)),
+		SignedBlocksFile:         option(cmd, signedBlocksFileFlag.Name, cfg.SignedBlocksFile, cmd.String),
+		RequestTimeoutGetHeader:  settings.RequestTimeoutGetHeader,
+		RequestTimeoutGetPayload: settings.RequestTimeoutGetPayload,
+		RequestTimeoutRegVal:     settings.RequestTimeoutRegVal,
+		RequestMaxRetries:        settings.RequestMaxRetries,
+		TimingGames:              settings.TimingGames,
+		TimingGamesDelay:         settings.TimingGamesDelay,
+		TimingGamesPollInterval:  settings.TimingGamesPollInterval,
+		GetHeaderDeadline:        settings.GetHeaderDeadline,
+		GetHeaderCutoff:          settings.GetHeaderCutoff,
+		GetPayloadDeadline:       settings.GetPayloadDeadline,
 	}
 	service, err := server.NewBoostService(opts)
 	if err != nil {
 		log.WithError(err).Fatal("failed creating the server")
 	}
+	defer func() {
+		if err := service.Close(); err != nil {
+			log.WithError(err).Error("failed closing the server")
+		}
+	}()
 
-	if relayCheck && service.CheckRelays() == 0 {
-		log.Error("no relay passed the health-check!")
+	if relayCheck {
+		if service.CheckRelays(ctx) == 0 {
+			log.Error("no relay passed the health-check!")
+		}
+		go service.RunRelayHealthChecker(ctx)
+	}
+
+	if metricsAddr != "" {
+		log.Infof("Metrics listening on %v", metricsAddr)
+		go func() {
+			if err := service.StartMetricsServer(); err != nil {
+				log.WithError(err).Error("metrics server failed")
+			}
+		}()
+	}
+
+	if cmd.IsSet(configFlag.Name) {
+		go watchConfigFile(ctx, cmd, service, cmd.String(configFlag.Name))
 	}
 
 	log.Infof("Listening on %v", listenAddr)
 	return service.StartHTTPServer()
 }
 
-func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str, bool) {
+// parseRelays returns the relays set with the -relays flag and in the config file
+func parseRelays(cmd *cli.Command, cfg *configFile) (relayList, error) {
 	// For backwards compatibility with the -relays flag.
-	var (
-		relays   relayList
-		monitors relayMonitorList
-	)
+	var relays relayList
 	if cmd.IsSet(relaysFlag.Name) {
 		relayURLs := cmd.StringSlice(relaysFlag.Name)
 		for _, urls := range relayURLs {
 			for _, url := range strings.Split(urls, ",") {
 				if err := relays.Set(strings.TrimSpace(url)); err != nil {
-					log.WithError(err).WithField("relay", url).Fatal("Invalid relay URL")
+					return nil, fmt.Errorf("invalid relay URL %s: %w", url, err)
 				}
 			}
 		}
 	}
 
+	// Relays from the config file, with their per-relay settings
+	configRelays, err := cfg.relayEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, relay := range configRelays {
+		if relays.Contains(relay) {
+			return nil, fmt.Errorf("%w: %s", errDuplicateEntry, relay.String())
+		}
+		relays = append(relays, relay)
+	}
+	return relays, nil
+}
+
+func setupRelays(cmd *cli.Command, cfg *configFile) (relayList, relayMonitorList, types.U256Str, bool) {
+	var monitors relayMonitorList
+	relays, err := parseRelays(cmd, cfg)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid relay configuration")
+	}
+
 	if len(relays) == 0 {
 		log.Fatal("no relays specified")
 	}
 	log.Infof("using %d relays", len(relays))
 	for index, relay := range relays {
-		log.Infof("relay #%d: %s", index+1, relay.String())
+		if relay.Name != "" {
+			log.Infof("relay #%d: %s (%s)", index+1, relay.Name, relay.String())
+		} else {
+			log.Infof("relay #%d: %s", index+1, relay.String())
+		}
 	}
 
 	// For backwards compatibility with the -relay-monitors flag.
-	if cmd.IsSet(relayMonitorFlag.Name) {
-		monitorURLs := cmd.StringSlice(relayMonitorFlag.Name)
+	if cmd.IsSet(relayMonitorFlag.Name) || len(cfg.RelayMonitors) > 0 {
+		monitorURLs := option(cmd, relayMonitorFlag.Name, &cfg.RelayMonitors, cmd.StringSlice)
 		for _, urls := range monitorURLs {
 			for _, url := range strings.Split(urls, ",") {
 				if err := monitors.Set(strings.TrimSpace(url)); err != nil {
@@ -141,50 +227,191 @@ func setupRelays(cmd *cli.Command) (relayList, relayMonitorList, types.U256Str,
 		}
 	}
 
-	relayMinBidWei, err := sanitizeMinBid(cmd.Float(minBidFlag.Name))
+	minBid := option(cmd, minBidFlag.Name, cfg.MinBid, cmd.Float)
+	relayMinBidWei, err := sanitizeMinBid(minBid)
 	if err != nil {
 		log.WithError(err).Fatal("Failed sanitizing min bid")
 	}
 	if relayMinBidWei.BigInt().Sign() > 0 {
-		log.Infof("Min bid set to %v eth (%v wei)", cmd.Float(minBidFlag.Name), relayMinBidWei)
+		log.Infof("Min bid set to %v eth (%v wei)", minBid, relayMinBidWei)
+	}
+	return relays, monitors, *relayMinBidWei, option(cmd, relayCheckFlag.Name, cfg.RelayCheck, cmd.Bool)
+}
+
+// setupBidFilters returns the bid filters configured with flags or in the config file
+func setupBidFilters(cmd *cli.Command, cfg *configFile) ([]server.BidFilter, error) {
+	var filters []server.BidFilter
+	if maxBid := option(cmd, maxBidFlag.Name, cfg.MaxBid, cmd.Float); maxBid != 0 {
+		if maxBid < 0 {
+			return nil, errNegativeMaxBid
+		}
+		maxBidWei, err := common.FloatEthTo256Wei(maxBid)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Max bid set to %v eth (%v wei)", maxBid, maxBidWei)
+		filters = append(filters, server.MaxBidFilter{MaxBid: maxBidWei.BigInt()})
+	}
+
+	allow := option(cmd, extraDataAllowFlag.Name, cfg.ExtraDataAllow, cmd.String)
+	deny := option(cmd, extraDataDenyFlag.Name, cfg.ExtraDataDeny, cmd.String)
+	if allow != "" || deny != "" {
+		filter, err := server.NewExtraDataFilter(allow, deny)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(logrus.Fields{"allow": allow, "deny": deny}).Info("Filtering bids on block extra data")
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+// runtimeSettings returns the service settings which can be changed by reloading the config file
+func runtimeSettings(cmd *cli.Command, cfg *configFile, relays relayList, minBid types.U256Str) server.RuntimeSettings {
+	return server.RuntimeSettings{
+		Relays:                   relays,
+		RelayMinBid:              minBid,
+		RequestTimeoutGetHeader:  time.Duration(option(cmd, timeoutGetHeaderFlag.Name, cfg.TimeoutGetHeaderMs, cmd.Int)) * time.Millisecond,
+		RequestTimeoutGetPayload: time.Duration(option(cmd, timeoutGetPayloadFlag.Name, cfg.TimeoutGetPayloadMs, cmd.Int)) * time.Millisecond,
+		RequestTimeoutRegVal:     time.Duration(option(cmd, timeoutRegValFlag.Name, cfg.TimeoutRegValMs, cmd.Int)) * time.Millisecond,
+		RequestMaxRetries:        int(option(cmd, maxRetriesFlag.Name, cfg.MaxRetries, cmd.Int)),
+		TimingGames:              option(cmd, timingGamesFlag.Name, cfg.TimingGames, cmd.Bool),
+		TimingGamesDelay:         time.Duration(option(cmd, timingGamesDelayFlag.Name, cfg.TimingGamesDelayMs, cmd.Int)) * time.Millisecond,
+		TimingGamesPollInterval:  time.Duration(option(cmd, timingGamesPollIntervalFlag.Name, cfg.TimingGamesPollIntervalMs, cmd.Int)) * time.Millisecond,
+		GetHeaderDeadline:        time.Duration(option(cmd, deadlineGetHeaderFlag.Name, cfg.DeadlineGetHeaderMs, cmd.Int)) * time.Millisecond,
+		GetHeaderCutoff:          time.Duration(option(cmd, cutoffGetHeaderFlag.Name, cfg.CutoffGetHeaderMs, cmd.Int)) * time.Millisecond,
+		GetPayloadDeadline:       time.Duration(option(cmd, deadlineGetPayloadFlag.Name, cfg.DeadlineGetPayloadMs, cmd.Int)) * time.Millisecond,
 	}
-	return relays, monitors, *relayMinBidWei, cmd.Bool(relayCheckFlag.Name)
 }
 
-func setupGenesis(cmd *cli.Command) (string, uint64) {
+// setupGenesis returns the genesis fork version and time, and the fork schedule of the network
+func setupGenesis(cmd *cli.Command, cfg *configFile) (string, uint64, *serverTypes.ForkSchedule) {
 	var (
 		genesisForkVersion string
 		genesisTime        uint64
+		forkSchedule       *serverTypes.ForkSchedule
 	)
 
+	// The network from the config file applies unless a network flag is set on the command line
+	network := ""
+	if cfg.Network != nil && !cmd.IsSet(sepoliaFlag.Name) && !cmd.IsSet(holeskyFlag.Name) && !cmd.IsSet(mainnetFlag.Name) {
+		network = *cfg.Network
+	}
+	customGenesisFork := option(cmd, customGenesisForkFlag.Name, cfg.GenesisFork, cmd.String)
+	networkConfigPath := option(cmd, networkConfigFlag.Name, cfg.NetworkConfig, cmd.String)
+
 	switch {
-	case cmd.Bool(customGenesisForkFlag.Name):
-		genesisForkVersion = cmd.String(customGenesisForkFlag.Name)
-	case cmd.Bool(sepoliaFlag.Name):
+	case networkConfigPath != "":
+		networkConfig, err := loadNetworkConfig(networkConfigPath)
+		if err != nil {
+			log.WithError(err).Fatal("failed loading network config")
+		}
+		genesisForkVersion = networkConfig.genesisForkVersion
+		genesisTime = networkConfig.genesisTime
+		forkSchedule = networkConfig.forkSchedule
+
+		// SLOT_SEC and the config file take precedence over the network config
+		if _, ok := os.LookupEnv("SLOT_SEC"); !ok && cfg.Server.SlotTimeSec == nil {
+			config.SlotTimeSec = networkConfig.secondsPerSlot
+		}
+		log.Infof("using network config %s, slot time: %d seconds", networkConfigPath, config.SlotTimeSec)
+
+		// An explicit genesis fork version takes precedence over the network config
+		if customGenesisFork != "" {
+			genesisForkVersion = customGenesisFork
+		}
+	case customGenesisFork != "":
+		genesisForkVersion = customGenesisFork
+	case network == "sepolia", network == "" && cmd.Bool(sepoliaFlag.Name):
 		genesisForkVersion = genesisForkVersionSepolia
 		genesisTime = genesisTimeSepolia
-	case cmd.Bool(holeskyFlag.Name):
+		forkSchedule = serverTypes.ForkScheduleSepolia
+	case network == "holesky", network == "" && cmd.Bool(holeskyFlag.Name):
 		genesisForkVersion = genesisForkVersionHolesky
 		genesisTime = genesisTimeHolesky
-	case cmd.Bool(mainnetFlag.Name):
+		forkSchedule = serverTypes.ForkScheduleHolesky
+	case network == "mainnet", network == "" && cmd.Bool(mainnetFlag.Name):
 		genesisForkVersion = genesisForkVersionMainnet
 		genesisTime = genesisTimeMainnet
+		forkSchedule = serverTypes.ForkScheduleMainnet
 	default:
 		flag.Usage()
-		log.Fatal("please specify a genesis fork version (eg. -mainnet / -sepolia / -goerli / -holesky / -genesis-fork-version flags)")
+		log.Fatal("please specify a genesis fork version (eg. -mainnet / -sepolia / -holesky / -network-config / -genesis-fork-version flags)")
 	}
 
-	if cmd.IsSet(customGenesisTimeFlag.Name) {
-		genesisTime = cmd.Uint(customGenesisTimeFlag.Name)
+	if cmd.IsSet(customGenesisTimeFlag.Name) || cfg.GenesisTime != nil {
+		genesisTime = option(cmd, customGenesisTimeFlag.Name, cfg.GenesisTime, cmd.Uint)
 	}
 	log.Infof("using genesis fork version: %s time: %d", genesisForkVersion, genesisTime)
-	return genesisForkVersion, genesisTime
+
+	forkSchedule, err := setupForkSchedule(cmd, cfg, forkSchedule)
+	if err != nil {
+		log.WithError(err).Fatal("invalid fork schedule")
+	}
+	if forkSchedule == nil {
+		log.Warn("no fork schedule for this network, use -fork-epochs to select the fork by slot")
+	} else {
+		for _, fork := range forkSchedule.Forks() {
+			log.Debugf("fork %s at epoch %d", fork.Version, fork.Epoch)
+		}
+	}
+	return genesisForkVersion, genesisTime, forkSchedule
+}
+
+// setupForkSchedule applies the fork epochs set with -fork-epochs or in the config file to the network's fork schedule.
+// Networks without a preset get a custom schedule made of these epochs only.
+func setupForkSchedule(cmd *cli.Command, cfg *configFile, schedule *serverTypes.ForkSchedule) (*serverTypes.ForkSchedule, error) {
+	epochs, err := parseForkEpochs(cmd, cfg)
+	if err != nil || len(epochs) == 0 {
+		return schedule, err
+	}
+	if schedule == nil {
+		schedule, err = serverTypes.NewForkSchedule(nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schedule.WithEpochs(epochs)
 }
 
-func setupLogging(cmd *cli.Command) error {
+// parseForkEpochs returns the fork epochs of the -fork-epochs flag, or else of the config file
+func parseForkEpochs(cmd *cli.Command, cfg *configFile) (map[spec.DataVersion]uint64, error) {
+	epochs := make(map[spec.DataVersion]uint64)
+	if !cmd.IsSet(forkEpochsFlag.Name) {
+		for name, epoch := range cfg.ForkEpochs {
+			version, err := serverTypes.ParseDataVersion(name)
+			if err != nil {
+				return nil, err
+			}
+			epochs[version] = epoch
+		}
+		return epochs, nil
+	}
+
+	for _, entries := range cmd.StringSlice(forkEpochsFlag.Name) {
+		for _, entry := range strings.Split(entries, ",") {
+			name, epochStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+			if !found {
+				return nil, fmt.Errorf("%w: %s", errInvalidForkEpoch, entry)
+			}
+			version, err := serverTypes.ParseDataVersion(name)
+			if err != nil {
+				return nil, err
+			}
+			epoch, err := strconv.ParseUint(epochStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", errInvalidForkEpoch, entry)
+			}
+			epochs[version] = epoch
+		}
+	}
+	return epochs, nil
+}
+
+func setupLogging(cmd *cli.Command, cfg *configFile) error {
 	// setup logging
 	log.Logger.SetOutput(os.Stdout)
-	if cmd.IsSet(jsonFlag.Name) {
+	if option(cmd, jsonFlag.Name, cfg.LogJSON, cmd.Bool) {
 		log.Logger.SetFormatter(&logrus.JSONFormatter{
 			TimestampFormat: config.RFC3339Milli,
 		})
@@ -195,22 +422,18 @@ func setupLogging(cmd *cli.Command) error {
 		})
 	}
 
-	logLevel := cmd.String(logLevelFlag.Name)
-	if cmd.IsSet(debugFlag.Name) {
-		logLevel = "debug"
-	}
-	lvl, err := logrus.ParseLevel(logLevel)
+	lvl, err := parseLogLevel(cmd, cfg)
 	if err != nil {
-		return fmt.Errorf("%w: %s", errInvalidLoglevel, logLevel)
+		return err
 	}
 	log.Logger.SetLevel(lvl)
 
-	if cmd.IsSet(logServiceFlag.Name) {
-		log = log.WithField("service", cmd.String(logServiceFlag.Name))
+	if logService := option(cmd, logServiceFlag.Name, cfg.LogService, cmd.String); logService != "" {
+		log = log.WithField("service", logService)
 	}
 
 	// Add version to logs and say hello
-	if cmd.Bool(logNoVersionFlag.Name) {
+	if option(cmd, logNoVersionFlag.Name, cfg.LogNoVersion, cmd.Bool) {
 		log.Infof("starting mev-boost %s", config.Version)
 	} else {
 		log = log.WithField("version", config.Version)
@@ -220,6 +443,18 @@ func setupLogging(cmd *cli.Command) error {
 	return nil
 }
 
+func parseLogLevel(cmd *cli.Command, cfg *configFile) (logrus.Level, error) {
+	logLevel := option(cmd, logLevelFlag.Name, cfg.LogLevel, cmd.String)
+	if cmd.IsSet(debugFlag.Name) {
+		logLevel = "debug"
+	}
+	lvl, err := logrus.ParseLevel(logLevel)
+	if err != nil {
+		return lvl, fmt.Errorf("%w: %s", errInvalidLoglevel, logLevel)
+	}
+	return lvl, nil
+}
+
 func sanitizeMinBid(minBid float64) (*types.U256Str, error) {
 	if minBid < 0.0 {
 		return nil, errNegativeBid
@@ -4,8 +4,11 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/flashbots/go-boost-utils/types"
 	"github.com/flashbots/mev-boost/common"
+	"github.com/flashbots/mev-boost/server"
+	serverTypes "github.com/flashbots/mev-boost/server/types"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,3 +38,62 @@ func TestFloatEthTo256Wei(t *testing.T) {
 
 	require.Equal(t, *referenceWeiU256, *weiU256)
 }
+
+func TestSetupForkSchedule(t *testing.T) {
+	// Flags keep their set state once parsed, so the subtests setting -fork-epochs run last
+	t.Run("Custom schedule from config file", func(t *testing.T) {
+		cfg := &configFile{ForkEpochs: map[string]uint64{"deneb": 0, "electra": 10}}
+		schedule, err := setupForkSchedule(parsedCommand(t), cfg, nil)
+		require.NoError(t, err)
+		require.Equal(t, spec.DataVersionDeneb, schedule.VersionAtSlot(0))
+		require.Equal(t, spec.DataVersionElectra, schedule.VersionAtSlot(10*serverTypes.SlotsPerEpoch))
+	})
+
+	t.Run("No fork epochs", func(t *testing.T) {
+		schedule, err := setupForkSchedule(parsedCommand(t), new(configFile), nil)
+		require.NoError(t, err)
+		require.Nil(t, schedule)
+	})
+
+	t.Run("Flag overrides network fork epochs", func(t *testing.T) {
+		cmd := parsedCommand(t, "-fork-epochs", "electra=400000")
+		schedule, err := setupForkSchedule(cmd, new(configFile), serverTypes.ForkScheduleMainnet)
+		require.NoError(t, err)
+		require.Equal(t, spec.DataVersionDeneb, schedule.VersionAtSlot(364032*serverTypes.SlotsPerEpoch))
+		require.Equal(t, spec.DataVersionElectra, schedule.VersionAtSlot(400000*serverTypes.SlotsPerEpoch))
+	})
+
+	t.Run("Invalid fork epochs", func(t *testing.T) {
+		_, err := setupForkSchedule(parsedCommand(t, "-fork-epochs", "electra"), new(configFile), nil)
+		require.ErrorIs(t, err, errInvalidForkEpoch)
+
+		_, err = setupForkSchedule(parsedCommand(t, "-fork-epochs", "foo=1"), new(configFile), nil)
+		require.ErrorIs(t, err, serverTypes.ErrUnknownFork)
+	})
+}
+
+func TestSetupBidFilters(t *testing.T) {
+	t.Run("No filters", func(t *testing.T) {
+		filters, err := setupBidFilters(parsedCommand(t), new(configFile))
+		require.NoError(t, err)
+		require.Empty(t, filters)
+	})
+
+	t.Run("Filters from config file", func(t *testing.T) {
+		maxBid, deny := 10.0, "^unwanted"
+		filters, err := setupBidFilters(parsedCommand(t), &configFile{MaxBid: &maxBid, ExtraDataDeny: &deny})
+		require.NoError(t, err)
+		require.Len(t, filters, 2)
+		require.Equal(t, server.MaxBidFilter{MaxBid: new(big.Int).Mul(big.NewInt(10), big.NewInt(1e18))}, filters[0])
+		require.Equal(t, "^unwanted", filters[1].(*server.ExtraDataFilter).Deny.String())
+		require.Nil(t, filters[1].(*server.ExtraDataFilter).Allow)
+	})
+
+	t.Run("Invalid filters", func(t *testing.T) {
+		maxBid, allow := -1.0, "("
+		_, err := setupBidFilters(parsedCommand(t), &configFile{MaxBid: &maxBid})
+		require.ErrorIs(t, err, errNegativeMaxBid)
+		_, err = setupBidFilters(parsedCommand(t), &configFile{ExtraDataAllow: &allow})
+		require.Error(t, err)
+	})
+}
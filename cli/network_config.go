@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	serverTypes "github.com/flashbots/mev-boost/server/types"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	networkConfigFile = "config.yaml"
+	genesisStateFile  = "genesis.ssz"
+)
+
+var (
+	errNetworkConfigMissingKey = errors.New("missing key in network config")
+	errUnsupportedPreset       = errors.New("unsupported preset, only the mainnet preset is supported")
+)
+
+// networkConfig holds the network parameters read from a consensus-layer config.yaml, and optionally genesis.ssz
+type networkConfig struct {
+	genesisForkVersion string
+	genesisTime        uint64
+	secondsPerSlot     uint64
+	forkSchedule       *serverTypes.ForkSchedule
+}
+
+// loadNetworkConfig reads the consensus-layer config.yaml at path, or in the directory at path. If a genesis.ssz
+// is present in the same directory, the genesis time is read from the genesis state instead of being derived from
+// MIN_GENESIS_TIME and GENESIS_DELAY.
+func loadNetworkConfig(path string) (*networkConfig, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, networkConfigFile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]yaml.Node)
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("could not parse network config %s: %w", path, err)
+	}
+	getUint := func(key string) (uint64, error) {
+		node, ok := values[key]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s", errNetworkConfigMissingKey, key)
+		}
+		return strconv.ParseUint(node.Value, 0, 64)
+	}
+	getVersion := func(key string) (phase0.Version, error) {
+		var version phase0.Version
+		node, ok := values[key]
+		if !ok {
+			return version, fmt.Errorf("%w: %s", errNetworkConfigMissingKey, key)
+		}
+		b, err := hexutil.Decode(node.Value)
+		if err != nil || len(b) != len(version) {
+			return version, fmt.Errorf("invalid %s: %s", key, node.Value)
+		}
+		copy(version[:], b)
+		return version, nil
+	}
+
+	// The fork schedule assumes the epoch length of the mainnet preset, which the minimal preset shortens
+	if node, ok := values["PRESET_BASE"]; ok && node.Value != "mainnet" {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedPreset, node.Value)
+	}
+
+	cfg := new(networkConfig)
+	genesisForkVersion, err := getVersion("GENESIS_FORK_VERSION")
+	if err != nil {
+		return nil, err
+	}
+	cfg.genesisForkVersion = hexutil.Encode(genesisForkVersion[:])
+	if cfg.secondsPerSlot, err = getUint("SECONDS_PER_SLOT"); err != nil {
+		return nil, err
+	}
+
+	// Forks present in the config and supported by mev-boost make up the fork schedule
+	forks := []serverTypes.Fork{{Version: spec.DataVersionPhase0, ForkVersion: genesisForkVersion, Epoch: 0}}
+	for _, version := range []spec.DataVersion{spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella, spec.DataVersionDeneb, spec.DataVersionElectra} {
+		prefix := strings.ToUpper(version.String())
+		if _, ok := values[prefix+"_FORK_EPOCH"]; !ok {
+			continue
+		}
+		epoch, err := getUint(prefix + "_FORK_EPOCH")
+		if err != nil {
+			return nil, err
+		}
+		forkVersion, err := getVersion(prefix + "_FORK_VERSION")
+		if err != nil {
+			return nil, err
+		}
+		forks = append(forks, serverTypes.Fork{Version: version, ForkVersion: forkVersion, Epoch: epoch})
+	}
+	if cfg.forkSchedule, err = serverTypes.NewForkSchedule(forks); err != nil {
+		return nil, err
+	}
+
+	genesisStatePath := filepath.Join(filepath.Dir(path), genesisStateFile)
+	if _, err := os.Stat(genesisStatePath); err == nil {
+		cfg.genesisTime, err = readGenesisTime(genesisStatePath)
+		return cfg, err
+	}
+
+	minGenesisTime, err := getUint("MIN_GENESIS_TIME")
+	if err != nil {
+		return nil, err
+	}
+	genesisDelay, err := getUint("GENESIS_DELAY")
+	if err != nil {
+		return nil, err
+	}
+	cfg.genesisTime = minGenesisTime + genesisDelay
+	return cfg, nil
+}
+
+// readGenesisTime returns the genesis time of an SSZ encoded genesis state, which is its first field
+func readGenesisTime(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var genesisTime [8]byte
+	if _, err := io.ReadFull(f, genesisTime[:]); err != nil {
+		return 0, fmt.Errorf("could not read genesis time from %s: %w", path, err)
+	}
+	return binary.LittleEndian.Uint64(genesisTime[:]), nil
+}
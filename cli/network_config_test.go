@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/stretchr/testify/require"
+)
+
+const testNetworkConfig = `
+PRESET_BASE: 'mainnet'
+CONFIG_NAME: 'devnet'
+TERMINAL_TOTAL_DIFFICULTY: 115792089237316195423570985008687907853269984665640564039457584007913129638912
+MIN_GENESIS_TIME: 1700000000
+GENESIS_DELAY: 60
+GENESIS_FORK_VERSION: 0x10000038
+ALTAIR_FORK_VERSION: 0x20000038
+ALTAIR_FORK_EPOCH: 0
+BELLATRIX_FORK_VERSION: 0x30000038
+BELLATRIX_FORK_EPOCH: 0
+CAPELLA_FORK_VERSION: 0x40000038
+CAPELLA_FORK_EPOCH: 0
+DENEB_FORK_VERSION: 0x50000038
+DENEB_FORK_EPOCH: 0
+ELECTRA_FORK_VERSION: 0x60000038
+ELECTRA_FORK_EPOCH: 10
+SECONDS_PER_SLOT: 6
+BLOB_SCHEDULE:
+  - EPOCH: 10
+    MAX_BLOBS_PER_BLOCK: 9
+`
+
+func TestLoadNetworkConfig(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, networkConfigFile), []byte(testNetworkConfig), 0o600))
+
+	t.Run("Genesis time from config.yaml", func(t *testing.T) {
+		cfg, err := loadNetworkConfig(filepath.Join(dir, networkConfigFile))
+		require.NoError(t, err)
+		require.Equal(t, "0x10000038", cfg.genesisForkVersion)
+		require.Equal(t, uint64(1700000060), cfg.genesisTime)
+		require.Equal(t, uint64(6), cfg.secondsPerSlot)
+
+		forks := cfg.forkSchedule.Forks()
+		require.Len(t, forks, 6)
+		require.Equal(t, spec.DataVersionElectra, forks[5].Version)
+		require.Equal(t, [4]byte{0x60, 0x00, 0x00, 0x38}, [4]byte(forks[5].ForkVersion))
+		require.Equal(t, spec.DataVersionDeneb, cfg.forkSchedule.VersionAtSlot(10*32-1))
+		require.Equal(t, spec.DataVersionElectra, cfg.forkSchedule.VersionAtSlot(10*32))
+	})
+
+	t.Run("Genesis time from genesis.ssz in directory", func(t *testing.T) {
+		state := make([]byte, 64)
+		binary.LittleEndian.PutUint64(state, 1700000123)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, genesisStateFile), state, 0o600))
+
+		cfg, err := loadNetworkConfig(dir)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1700000123), cfg.genesisTime)
+	})
+
+	t.Run("Missing key", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), networkConfigFile)
+		require.NoError(t, os.WriteFile(path, []byte("GENESIS_FORK_VERSION: 0x10000038\n"), 0o600))
+		_, err := loadNetworkConfig(path)
+		require.ErrorIs(t, err, errNetworkConfigMissingKey)
+	})
+
+	t.Run("Minimal preset", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), networkConfigFile)
+		config := strings.Replace(testNetworkConfig, "PRESET_BASE: 'mainnet'", "PRESET_BASE: 'minimal'", 1)
+		require.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+		_, err := loadNetworkConfig(path)
+		require.ErrorIs(t, err, errUnsupportedPreset)
+	})
+}
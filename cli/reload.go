@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/flashbots/mev-boost/server"
+	"github.com/urfave/cli/v3"
+)
+
+// configWatchInterval is how often the config file is checked for changes
+const configWatchInterval = 5 * time.Second
+
+var errNoRelays = errors.New("no relays specified")
+
+// watchConfigFile reloads the config file on SIGHUP, and whenever its modification time changes
+func watchConfigFile(ctx context.Context, cmd *cli.Command, service *server.BoostService, path string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	ticker := time.NewTicker(configWatchInterval)
+	defer ticker.Stop()
+
+	lastModTime := fileModTime(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			log.Info("received SIGHUP, reloading config file")
+		case <-ticker.C:
+			if fileModTime(path).Equal(lastModTime) {
+				continue
+			}
+			log.Info("config file changed, reloading")
+		}
+
+		lastModTime = fileModTime(path)
+		if err := reloadConfigFile(ctx, cmd, service, path); err != nil {
+			log.WithError(err).Error("failed reloading config file, keeping previous settings")
+		}
+	}
+}
+
+// reloadConfigFile applies the relays, min-bid, timeouts and log level of the config file to the running service.
+// Relays added by the config file are checked right away if relay checks are enabled.
+func reloadConfigFile(ctx context.Context, cmd *cli.Command, service *server.BoostService, path string) error {
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+
+	relays, err := parseRelays(cmd, cfg)
+	if err != nil {
+		return err
+	}
+	if len(relays) == 0 {
+		return errNoRelays
+	}
+	minBid, err := sanitizeMinBid(option(cmd, minBidFlag.Name, cfg.MinBid, cmd.Float))
+	if err != nil {
+		return err
+	}
+	lvl, err := parseLogLevel(cmd, cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := service.UpdateSettings(runtimeSettings(cmd, cfg, relays, *minBid)); err != nil {
+		return err
+	}
+	log.Logger.SetLevel(lvl)
+
+	log.Infof("config file reloaded, using %d relays", len(relays))
+	for index, relay := range relays {
+		log.Infof("relay #%d: %s", index+1, relay.String())
+	}
+	service.CheckNewRelays(ctx)
+	return nil
+}
+
+// fileModTime returns the modification time of a file, or the zero time if it cannot be read
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
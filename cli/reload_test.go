@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/flashbots/mev-boost/server"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v3"
+)
+
+// parsedCommand returns a command with all mev-boost flags parsed from args
+func parsedCommand(t *testing.T, args ...string) *cli.Command {
+	t.Helper()
+	var parsed *cli.Command
+	cmd := &cli.Command{
+		Name:  "mev-boost",
+		Flags: flags,
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			parsed = cmd
+			return nil
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), append([]string{"mev-boost"}, args...)))
+	return parsed
+}
+
+func TestReloadConfigFile(t *testing.T) {
+	relay, err := types.NewRelayEntry("http://" + testRelayPubkey + "@relay-a.example.com")
+	require.NoError(t, err)
+	service, err := server.NewBoostService(server.BoostServiceOpts{
+		Log:                     log,
+		Relays:                  []types.RelayEntry{relay},
+		GenesisForkVersionHex:   genesisForkVersionMainnet,
+		RequestTimeoutGetHeader: time.Second,
+	})
+	require.NoError(t, err)
+
+	t.Run("Valid config file", func(t *testing.T) {
+		path := writeConfigFile(t, `
+log_level: debug
+relays:
+  - url: https://`+testRelayPubkey+`@relay-b.example.com
+`)
+		cmd := parsedCommand(t, "-config", path)
+		require.NoError(t, reloadConfigFile(context.Background(), cmd, service, path))
+	})
+
+	t.Run("Config file without relays", func(t *testing.T) {
+		path := writeConfigFile(t, "min_bid: 0.1\n")
+		cmd := parsedCommand(t, "-config", path)
+		require.ErrorIs(t, reloadConfigFile(context.Background(), cmd, service, path), errNoRelays)
+	})
+
+	t.Run("Invalid log level", func(t *testing.T) {
+		path := writeConfigFile(t, `
+log_level: loud
+relays:
+  - url: https://`+testRelayPubkey+`@relay-b.example.com
+`)
+		cmd := parsedCommand(t, "-config", path)
+		require.ErrorIs(t, reloadConfigFile(context.Background(), cmd, service, path), errInvalidLoglevel)
+	})
+}
@@ -31,5 +31,8 @@ var (
 	// SkipRelaySignatureCheck can be used to disable relay signature check
 	SkipRelaySignatureCheck = os.Getenv("SKIP_RELAY_SIGNATURE_CHECK") == "1"
 
+	// BeaconNodeTimeoutMarginMs is subtracted from the timeout advertised by the beacon node with the X-Timeout-Ms header
+	BeaconNodeTimeoutMarginMs = common.GetEnvInt("BEACON_NODE_TIMEOUT_MARGIN_MS", 100)
+
 	SlotTimeSec = uint64(common.GetEnvInt("SLOT_SEC", common.SlotTimeSecMainnet))
 )
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// bidCacheRetentionSlots is the number of slots before the current slot for which the bids returned for
+	// getHeader are kept in memory, about 3 minutes with 12 second slots
+	bidCacheRetentionSlots = 16
+	// bidCacheMaxBids bounds the number of bids kept in memory, the bids of the oldest slots are evicted first
+	bidCacheMaxBids = 1024
+)
+
+var (
+	bidCacheBids = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "bid_cache_bids",
+		Help:      "Number of bids returned for getHeader kept in memory for getPayload",
+	})
+
+	bidCacheLookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "bid_cache_lookups_total",
+		Help:      "Number of getPayload lookups of the bid in memory, by result: hit or miss",
+	}, []string{"result"})
+
+	bidCacheEvictionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "bid_cache_evictions_total",
+		Help:      "Number of bids evicted from memory, by reason: slot (out of the retention) or size (over the bound)",
+	}, []string{"reason"})
+)
+
+func init() {
+	prometheus.MustRegister(bidCacheBids, bidCacheLookupsTotal, bidCacheEvictionsTotal)
+}
+
+// bidCacheSnapshot is a version of the cached bids, by slot and block hash. It is never modified once published.
+type bidCacheSnapshot struct {
+	slots   map[uint64]map[string]bidResp
+	numBids int
+}
+
+// bidCache keeps the bids returned for getHeader, keyed by slot and block hash, to route getPayload and attribute
+// withholding. Reads are lock-free: writers publish a new snapshot of the bids, so getPayload never waits on
+// getHeader. Bids are evicted at each slot start once out of the retention, and by oldest slot above the size bound.
+// Without a genesis time, the slot clock is unknown and only the size bound applies.
+type bidCache struct {
+	genesisTime    uint64
+	slotDuration   time.Duration
+	retentionSlots uint64
+	maxBids        int
+
+	snapshot  atomic.Pointer[bidCacheSnapshot]
+	writeLock sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newBidCache returns a bid cache evicting bids on the slot clock until ctx is done or the cache is closed
+func newBidCache(ctx context.Context, genesisTime uint64, slotDuration time.Duration, retentionSlots uint64, maxBids int) *bidCache {
+	ctx, cancel := context.WithCancel(ctx)
+	c := &bidCache{
+		genesisTime:    genesisTime,
+		slotDuration:   slotDuration,
+		retentionSlots: retentionSlots,
+		maxBids:        maxBids,
+		cancel:         cancel,
+		done:           make(chan struct{}),
+	}
+	c.snapshot.Store(&bidCacheSnapshot{slots: make(map[uint64]map[string]bidResp)})
+	go c.run(ctx)
+	return c
+}
+
+// Close stops the eviction of bids on the slot clock. The cache can still be used.
+func (c *bidCache) Close() {
+	c.cancel()
+	<-c.done
+}
+
+// run evicts the bids out of the retention at the start of each slot, if the slot clock is known
+func (c *bidCache) run(ctx context.Context) {
+	defer close(c.done)
+	if c.genesisTime == 0 || c.slotDuration <= 0 {
+		return
+	}
+	for {
+		slot, untilNextSlot := c.slotClock(time.Now())
+		timer := time.NewTimer(untilNextSlot)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		c.evictSlots(slot + 1)
+	}
+}
+
+// slotClock returns the current slot, and the time until the next one starts
+func (c *bidCache) slotClock(now time.Time) (uint64, time.Duration) {
+	genesis := time.Unix(int64(c.genesisTime), 0)
+	if now.Before(genesis) {
+		return 0, genesis.Sub(now)
+	}
+	slot := uint64(now.Sub(genesis) / c.slotDuration)
+	nextSlotStart := genesis.Add(time.Duration(slot+1) * c.slotDuration)
+	return slot, nextSlotStart.Sub(now)
+}
+
+// get returns the bid of a slot and block hash, without locking
+func (c *bidCache) get(key bidRespKey) (bidResp, bool) {
+	bid, ok := c.snapshot.Load().slots[key.slot][key.blockHash]
+	if ok {
+		bidCacheLookupsTotal.WithLabelValues("hit").Inc()
+	} else {
+		bidCacheLookupsTotal.WithLabelValues("miss").Inc()
+	}
+	return bid, ok
+}
+
+// find returns the bid of a block for a slot before the given slot, if any
+func (c *bidCache) find(blockHash string, beforeSlot uint64) (bidResp, bool) {
+	for slot, bids := range c.snapshot.Load().slots {
+		if bid, ok := bids[blockHash]; ok && slot < beforeSlot {
+			return bid, true
+		}
+	}
+	return bidResp{}, false
+}
+
+// put adds or replaces the bid of a slot and block hash, evicting the bids of the oldest slots above the size bound
+func (c *bidCache) put(key bidRespKey, bid bidResp) {
+	c.update(func(slots map[uint64]map[string]bidResp) {
+		bids := make(map[string]bidResp, len(slots[key.slot])+1)
+		for blockHash, bid := range slots[key.slot] {
+			bids[blockHash] = bid
+		}
+		bids[key.blockHash] = bid
+		slots[key.slot] = bids
+	}, key.slot)
+}
+
+// evictSlots evicts the bids out of the retention at the given slot
+func (c *bidCache) evictSlots(currentSlot uint64) {
+	if currentSlot < c.retentionSlots {
+		return
+	}
+	oldestSlot := currentSlot - c.retentionSlots
+	c.update(func(slots map[uint64]map[string]bidResp) {
+		for slot, bids := range slots {
+			if slot < oldestSlot {
+				delete(slots, slot)
+				bidCacheEvictionsTotal.WithLabelValues("slot").Add(float64(len(bids)))
+			}
+		}
+	}, oldestSlot)
+}
+
+// update publishes a new snapshot with the changes of modify, applied to a copy of the slots, then evicts the bids
+// of the oldest slots before keepSlot above the size bound
+func (c *bidCache) update(modify func(slots map[uint64]map[string]bidResp), keepSlot uint64) {
+	c.writeLock.Lock()
+	defer c.writeLock.Unlock()
+
+	previous := c.snapshot.Load()
+	slots := make(map[uint64]map[string]bidResp, len(previous.slots)+1)
+	for slot, bids := range previous.slots {
+		slots[slot] = bids
+	}
+	modify(slots)
+
+	numBids := 0
+	oldestSlot := keepSlot
+	for slot, bids := range slots {
+		numBids += len(bids)
+		oldestSlot = min(oldestSlot, slot)
+	}
+	for numBids > c.maxBids && oldestSlot < keepSlot {
+		numBids -= len(slots[oldestSlot])
+		bidCacheEvictionsTotal.WithLabelValues("size").Add(float64(len(slots[oldestSlot])))
+		delete(slots, oldestSlot)
+		oldestSlot = keepSlot
+		for slot := range slots {
+			oldestSlot = min(oldestSlot, slot)
+		}
+	}
+
+	c.snapshot.Store(&bidCacheSnapshot{slots: slots, numBids: numBids})
+	bidCacheBids.Set(float64(numBids))
+}
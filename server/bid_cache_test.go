@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// bidCacheEvictions returns the number of bids evicted from the bid caches for a reason
+func bidCacheEvictions(reason string) float64 {
+	return testutil.ToFloat64(bidCacheEvictionsTotal.WithLabelValues(reason))
+}
+
+func TestBidCache(t *testing.T) {
+	cache := newBidCache(context.Background(), 0, 0, 2, 3)
+	defer cache.Close()
+	hash1 := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	hash2 := "0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	hits := testutil.ToFloat64(bidCacheLookupsTotal.WithLabelValues("hit"))
+	misses := testutil.ToFloat64(bidCacheLookupsTotal.WithLabelValues("miss"))
+	sizeEvictions := bidCacheEvictions("size")
+	slotEvictions := bidCacheEvictions("slot")
+
+	_, ok := cache.get(bidRespKey{slot: 10, blockHash: hash1})
+	require.False(t, ok)
+	cache.put(bidRespKey{slot: 10, blockHash: hash1}, bidResp{bidInfo: bidInfo{gasLimit: 1}})
+	cache.put(bidRespKey{slot: 10, blockHash: hash2}, bidResp{bidInfo: bidInfo{gasLimit: 2}})
+	bid, ok := cache.get(bidRespKey{slot: 10, blockHash: hash1})
+	require.True(t, ok)
+	require.Equal(t, uint64(1), bid.bidInfo.gasLimit)
+	_, ok = cache.get(bidRespKey{slot: 11, blockHash: hash1})
+	require.False(t, ok)
+
+	// Parent lookups only find bids of earlier slots
+	_, ok = cache.find(hash2, 10)
+	require.False(t, ok)
+	bid, ok = cache.find(hash2, 11)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), bid.bidInfo.gasLimit)
+
+	// Above the size bound, the oldest slot is evicted
+	snapshot := cache.snapshot.Load()
+	cache.put(bidRespKey{slot: 11, blockHash: hash1}, bidResp{})
+	cache.put(bidRespKey{slot: 12, blockHash: hash1}, bidResp{})
+	_, ok = cache.get(bidRespKey{slot: 10, blockHash: hash1})
+	require.False(t, ok)
+	require.Len(t, cache.snapshot.Load().slots, 2)
+	require.Equal(t, 2, cache.snapshot.Load().numBids)
+	require.InDelta(t, hits+1, testutil.ToFloat64(bidCacheLookupsTotal.WithLabelValues("hit")), 0)
+	require.InDelta(t, misses+3, testutil.ToFloat64(bidCacheLookupsTotal.WithLabelValues("miss")), 0)
+	require.InDelta(t, sizeEvictions+2, bidCacheEvictions("size"), 0)
+
+	// Earlier snapshots are never modified
+	require.Len(t, snapshot.slots, 1)
+	require.Equal(t, 2, snapshot.numBids)
+
+	// Out of the retention
+	cache.evictSlots(13)
+	require.Equal(t, 2, cache.snapshot.Load().numBids)
+	cache.evictSlots(14)
+	require.Equal(t, 1, cache.snapshot.Load().numBids)
+	require.InDelta(t, slotEvictions+1, bidCacheEvictions("slot"), 0)
+	_, ok = cache.get(bidRespKey{slot: 12, blockHash: hash1})
+	require.True(t, ok)
+}
+
+func TestBidCacheSlotClock(t *testing.T) {
+	now := time.Now()
+	cache := newBidCache(context.Background(), uint64(now.Unix())+10, 12*time.Second, 2, 3)
+	cache.Close()
+
+	// Before genesis
+	slot, untilNextSlot := cache.slotClock(now)
+	require.Equal(t, uint64(0), slot)
+	require.Equal(t, time.Unix(now.Unix()+10, 0).Sub(now), untilNextSlot)
+
+	// Into slot 2
+	slot, untilNextSlot = cache.slotClock(time.Unix(now.Unix()+10+2*12+5, 0))
+	require.Equal(t, uint64(2), slot)
+	require.Equal(t, 7*time.Second, untilNextSlot)
+}
+
+func TestBidCacheEviction(t *testing.T) {
+	// Slots of 50ms from now on, bids kept for one slot
+	slotDuration := 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	cache := newBidCache(ctx, uint64(time.Now().Unix()), slotDuration, 1, bidCacheMaxBids)
+
+	slot, _ := cache.slotClock(time.Now())
+	key := bidRespKey{slot: slot, blockHash: "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"}
+	cache.put(key, bidResp{})
+	_, ok := cache.get(key)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		return cache.snapshot.Load().numBids == 0
+	}, 10*slotDuration, slotDuration/5)
+	_, ok = cache.get(key)
+	require.False(t, ok)
+
+	// Eviction stops with the context
+	cancel()
+	select {
+	case <-cache.done:
+	case <-time.After(time.Second):
+		require.Fail(t, "eviction still running")
+	}
+	cache.Close()
+}
+
+func TestBidCacheWithoutGenesisTime(t *testing.T) {
+	// Without a genesis time, bids are not evicted on the slot clock
+	cache := newBidCache(context.Background(), 0, 12*time.Second, 1, 2)
+	select {
+	case <-cache.done:
+	case <-time.After(time.Second):
+		require.Fail(t, "eviction running without a genesis time")
+	}
+
+	hash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	cache.put(bidRespKey{slot: 1, blockHash: hash}, bidResp{})
+	_, ok := cache.get(bidRespKey{slot: 1, blockHash: hash})
+	require.True(t, ok)
+
+	// Only the size bound applies
+	cache.put(bidRespKey{slot: 2, blockHash: hash}, bidResp{})
+	cache.put(bidRespKey{slot: 3, blockHash: hash}, bidResp{})
+	_, ok = cache.get(bidRespKey{slot: 1, blockHash: hash})
+	require.False(t, ok)
+	_, ok = cache.get(bidRespKey{slot: 2, blockHash: hash})
+	require.True(t, ok)
+	cache.Close()
+}
+
+func TestBidCacheConcurrency(t *testing.T) {
+	cache := newBidCache(context.Background(), 0, 0, bidCacheRetentionSlots, 100)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for slot := range uint64(200) {
+				cache.put(bidRespKey{slot: slot, blockHash: string(rune('a' + i))}, bidResp{})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for slot := range uint64(200) {
+				cache.get(bidRespKey{slot: slot, blockHash: string(rune('a' + i))})
+				cache.find("a", slot)
+			}
+		}()
+	}
+	wg.Wait()
+	require.LessOrEqual(t, cache.snapshot.Load().numBids, 100)
+}
@@ -0,0 +1,320 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	errBidPubkeyMismatch     = errors.New("bid pubkey does not match the relay pubkey")
+	errInvalidRelaySignature = errors.New("failed to verify relay signature")
+	errBidParentHashMismatch = errors.New("proposer and relay parent hashes are not the same")
+	errBidZeroValue          = errors.New("bid with 0 value")
+	errBidBelowMinBid        = errors.New("bid below min-bid value")
+	errBidAboveMaxBid        = errors.New("bid above max-bid value")
+	errExtraDataDenied       = errors.New("extra data matches the deny list")
+	errExtraDataNotAllowed   = errors.New("extra data does not match the allow list")
+	errBidTimestampMismatch  = errors.New("bid timestamp does not match the slot")
+	errBidZeroBaseFee        = errors.New("bid with 0 base fee")
+	errBidGasUsedAboveLimit  = errors.New("bid gas used above gas limit")
+	errBidBlobGasUsed        = errors.New("bid blob gas used does not match the blobs")
+)
+
+// blockNumberFilterName identifies the block number check in the logs, like the bid filters
+const blockNumberFilterName = "block-number"
+
+// emptyListTxRoot is the transactions root of a block without transactions
+const emptyListTxRoot = "0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1"
+
+// GetHeaderRequest is the getHeader request of the beacon node a bid answers
+type GetHeaderRequest struct {
+	Slot       uint64
+	ParentHash string
+	Pubkey     string
+}
+
+// BidFilter rejects bids received for getHeader by returning an error. The validity checks of mev-boost are run
+// first, then the min-bid check and the filters of BoostServiceOpts in order, until a filter rejects the bid.
+type BidFilter interface {
+	// Name identifies the filter in the logs and metrics
+	Name() string
+	FilterBid(req GetHeaderRequest, bid *BidCandidate) error
+}
+
+// bidFilterFunc is a BidFilter calling a function, see NewBidFilter
+type bidFilterFunc struct {
+	name   string
+	filter func(req GetHeaderRequest, bid *BidCandidate) error
+}
+
+// NewBidFilter returns a BidFilter with the given name, rejecting the bids for which filter returns an error
+func NewBidFilter(name string, filter func(req GetHeaderRequest, bid *BidCandidate) error) BidFilter {
+	return bidFilterFunc{name: name, filter: filter}
+}
+
+func (f bidFilterFunc) Name() string { return f.name }
+
+func (f bidFilterFunc) FilterBid(req GetHeaderRequest, bid *BidCandidate) error {
+	return f.filter(req, bid)
+}
+
+// bidErrorClass returns the relay error class recorded for bids rejected by a filter, if any
+type bidErrorClass interface {
+	errorClass() string
+}
+
+// relayPubkeyFilter rejects bids signed by another key than the relay's
+type relayPubkeyFilter struct{}
+
+func (relayPubkeyFilter) Name() string       { return "relay-pubkey" }
+func (relayPubkeyFilter) errorClass() string { return errorClassSignature }
+
+func (relayPubkeyFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	if bid.Relay.PublicKey.String() != bid.bidInfo.pubkey.String() {
+		return fmt.Errorf("%w: expected %s, got %s", errBidPubkeyMismatch, bid.Relay.PublicKey.String(), bid.bidInfo.pubkey.String())
+	}
+	return nil
+}
+
+// relaySignatureFilter rejects bids without a valid relay signature
+type relaySignatureFilter struct {
+	domain phase0.Domain
+}
+
+func (relaySignatureFilter) Name() string       { return "relay-signature" }
+func (relaySignatureFilter) errorClass() string { return errorClassSignature }
+
+func (f relaySignatureFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	ok, err := checkRelaySignature(bid.Bid, f.domain, bid.Relay.PublicKey)
+	if err != nil {
+		return fmt.Errorf("error verifying relay signature: %w", err)
+	}
+	if !ok {
+		return errInvalidRelaySignature
+	}
+	return nil
+}
+
+// parentHashFilter rejects bids building on another block than requested by the proposer
+type parentHashFilter struct{}
+
+func (parentHashFilter) Name() string       { return "parent-hash" }
+func (parentHashFilter) errorClass() string { return errorClassParentHash }
+
+func (parentHashFilter) FilterBid(req GetHeaderRequest, bid *BidCandidate) error {
+	if bid.bidInfo.parentHash.String() != req.ParentHash {
+		return fmt.Errorf("%w: requested %s, got %s", errBidParentHashMismatch, req.ParentHash, bid.bidInfo.parentHash.String())
+	}
+	return nil
+}
+
+// timestampFilter rejects bids whose timestamp is not the start of the requested slot. Without a genesis time, as on
+// a custom network set up without -genesis-timestamp, the start of the slot is not known and bids are not checked.
+type timestampFilter struct {
+	genesisTime uint64
+}
+
+func (timestampFilter) Name() string       { return "timestamp" }
+func (timestampFilter) errorClass() string { return errorClassInvalid }
+
+func (f timestampFilter) FilterBid(req GetHeaderRequest, bid *BidCandidate) error {
+	if f.genesisTime == 0 {
+		return nil
+	}
+	if expected := f.genesisTime + req.Slot*config.SlotTimeSec; bid.bidInfo.header.timestamp != expected {
+		return fmt.Errorf("%w: expected %d, got %d", errBidTimestampMismatch, expected, bid.bidInfo.header.timestamp)
+	}
+	return nil
+}
+
+// headerFieldsFilter rejects bids with implausible execution payload header fields
+type headerFieldsFilter struct{}
+
+func (headerFieldsFilter) Name() string       { return "header-fields" }
+func (headerFieldsFilter) errorClass() string { return errorClassInvalid }
+
+func (headerFieldsFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	header := bid.bidInfo.header
+	if header.baseFeePerGas == nil || header.baseFeePerGas.IsZero() {
+		return errBidZeroBaseFee
+	}
+	if header.gasUsed > bid.bidInfo.gasLimit {
+		return fmt.Errorf("%w: gas used %d, gas limit %d", errBidGasUsedAboveLimit, header.gasUsed, bid.bidInfo.gasLimit)
+	}
+	if header.blobGasUsed%params.BlobTxBlobGasPerBlob != 0 || header.blobGasUsed/params.BlobTxBlobGasPerBlob != uint64(header.numBlobs) {
+		return fmt.Errorf("%w: blob gas used %d, %d blobs", errBidBlobGasUsed, header.blobGasUsed, header.numBlobs)
+	}
+	return nil
+}
+
+// zeroValueFilter rejects bids without value or without transactions
+type zeroValueFilter struct{}
+
+func (zeroValueFilter) Name() string { return "zero-value" }
+
+func (zeroValueFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	if bid.Value.Sign() == 0 || bid.bidInfo.txRoot.String() == emptyListTxRoot {
+		return errBidZeroValue
+	}
+	return nil
+}
+
+// minBidFilter rejects bids below the relay's min-bid, or the global min-bid for relays without one
+type minBidFilter struct {
+	minBid types.U256Str
+}
+
+func (minBidFilter) Name() string { return "min-bid" }
+
+func (f minBidFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	minBid := f.minBid
+	if bid.Relay.MinBid != nil {
+		minBid = *bid.Relay.MinBid
+	}
+	if bid.Value.Cmp(minBid.BigInt()) == -1 {
+		return errBidBelowMinBid
+	}
+	return nil
+}
+
+// MaxBidFilter rejects bids above a value in wei, as a sanity cap on bid values
+type MaxBidFilter struct {
+	MaxBid *big.Int
+}
+
+func (MaxBidFilter) Name() string { return "max-bid" }
+
+func (f MaxBidFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	if bid.Value.Cmp(f.MaxBid) == 1 {
+		return errBidAboveMaxBid
+	}
+	return nil
+}
+
+// ExtraDataFilter filters bids on the extra data of the execution payload header, usually holding the builder tag.
+// Bids matching Deny are rejected, and if Allow is set, bids not matching Allow are rejected too.
+type ExtraDataFilter struct {
+	Allow *regexp.Regexp
+	Deny  *regexp.Regexp
+}
+
+// NewExtraDataFilter returns an ExtraDataFilter for the allow and deny regular expressions, unset if empty
+func NewExtraDataFilter(allow, deny string) (*ExtraDataFilter, error) {
+	f := new(ExtraDataFilter)
+	var err error
+	if allow != "" {
+		if f.Allow, err = regexp.Compile(allow); err != nil {
+			return nil, fmt.Errorf("invalid extra data allow regexp: %w", err)
+		}
+	}
+	if deny != "" {
+		if f.Deny, err = regexp.Compile(deny); err != nil {
+			return nil, fmt.Errorf("invalid extra data deny regexp: %w", err)
+		}
+	}
+	return f, nil
+}
+
+func (*ExtraDataFilter) Name() string { return "extra-data" }
+
+func (f *ExtraDataFilter) FilterBid(_ GetHeaderRequest, bid *BidCandidate) error {
+	if f.Deny != nil && f.Deny.Match(bid.ExtraData) {
+		return fmt.Errorf("%w: %q", errExtraDataDenied, bid.ExtraData)
+	}
+	if f.Allow != nil && !f.Allow.Match(bid.ExtraData) {
+		return fmt.Errorf("%w: %q", errExtraDataNotAllowed, bid.ExtraData)
+	}
+	return nil
+}
+
+// rejectingBidFilter runs the filters on a bid in order, and returns the filter rejecting it if any, logging the
+// rejection with the filter name
+func rejectingBidFilter(filters []BidFilter, req GetHeaderRequest, bid *BidCandidate, log *logrus.Entry) BidFilter {
+	for _, filter := range filters {
+		err := filter.FilterBid(req, bid)
+		if err == nil {
+			continue
+		}
+		log = log.WithField("filter", filter.Name()).WithError(err)
+		if _, ok := filter.(bidErrorClass); ok {
+			log.Error("invalid bid")
+		} else {
+			log.Info("bid rejected by filter")
+		}
+		return filter
+	}
+	return nil
+}
+
+// validityBidFilters returns the filters rejecting invalid bids, run before any other filter
+func (m *BoostService) validityBidFilters(skipSignatureCheck bool) []BidFilter {
+	filters := []BidFilter{relayPubkeyFilter{}}
+	if !skipSignatureCheck {
+		filters = append(filters, relaySignatureFilter{domain: m.builderSigningDomain})
+	}
+	return append(filters, parentHashFilter{}, timestampFilter{genesisTime: m.genesisTime}, headerFieldsFilter{}, zeroValueFilter{})
+}
+
+// checkBlockNumbers drops the bids whose block number is not the one after the parent block. If the parent block is
+// not a bid mev-boost received, the block number sent by most relays is expected, and all bids are dropped if no
+// block number was sent by more relays than the others.
+func (m *BoostService) checkBlockNumbers(log *logrus.Entry, req GetHeaderRequest, candidates []BidCandidate) []BidCandidate {
+	if len(candidates) == 0 {
+		return candidates
+	}
+
+	var expected uint64
+	if parent, ok := m.knownBid(req.ParentHash, req.Slot); ok {
+		expected = parent.blockNumber + 1
+	} else {
+		relaysByBlockNumber := make(map[uint64]map[string]struct{})
+		for _, candidate := range candidates {
+			if relaysByBlockNumber[candidate.bidInfo.blockNumber] == nil {
+				relaysByBlockNumber[candidate.bidInfo.blockNumber] = make(map[string]struct{})
+			}
+			relaysByBlockNumber[candidate.bidInfo.blockNumber][candidate.Relay.String()] = struct{}{}
+		}
+		maxRelays, tie := 0, false
+		for blockNumber, relays := range relaysByBlockNumber {
+			switch {
+			case len(relays) > maxRelays:
+				expected, maxRelays, tie = blockNumber, len(relays), false
+			case len(relays) == maxRelays:
+				tie = true
+			}
+		}
+		if tie {
+			// Return no bid rather than guessing which relays are wrong
+			log.WithField("filter", blockNumberFilterName).Error("invalid bids, relays disagree on the block number")
+			for _, candidate := range candidates {
+				recordRelayError(candidate.Relay, methodGetHeader, errorClassInvalid)
+			}
+			return nil
+		}
+	}
+
+	valid := candidates[:0]
+	for _, candidate := range candidates {
+		if candidate.bidInfo.blockNumber == expected {
+			valid = append(valid, candidate)
+			continue
+		}
+		log.WithFields(logrus.Fields{
+			"filter":              blockNumberFilterName,
+			"url":                 candidate.Relay.String(),
+			"blockHash":           candidate.BlockHash.String(),
+			"blockNumber":         candidate.bidInfo.blockNumber,
+			"expectedBlockNumber": expected,
+		}).Error("invalid bid, inconsistent block number")
+		recordRelayError(candidate.Relay, methodGetHeader, errorClassInvalid)
+	}
+	return valid
+}
@@ -0,0 +1,244 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/flashbots/mev-boost/config"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraDataFilter(t *testing.T) {
+	filter, err := NewExtraDataFilter("^(builder-a|builder-b)", "unwanted")
+	require.NoError(t, err)
+	require.Equal(t, "extra-data", filter.Name())
+
+	for extraData, expectedErr := range map[string]error{
+		"builder-a":          nil,
+		"builder-b v1.2":     nil,
+		"builder-c":          errExtraDataNotAllowed,
+		"":                   errExtraDataNotAllowed,
+		"builder-a unwanted": errExtraDataDenied,
+	} {
+		err := filter.FilterBid(GetHeaderRequest{}, &BidCandidate{ExtraData: []byte(extraData)})
+		if expectedErr == nil {
+			require.NoError(t, err, extraData)
+		} else {
+			require.ErrorIs(t, err, expectedErr, extraData)
+		}
+	}
+
+	t.Run("Deny only", func(t *testing.T) {
+		filter, err := NewExtraDataFilter("", "unwanted")
+		require.NoError(t, err)
+		require.NoError(t, filter.FilterBid(GetHeaderRequest{}, &BidCandidate{ExtraData: []byte("builder-c")}))
+		require.ErrorIs(t, filter.FilterBid(GetHeaderRequest{}, &BidCandidate{ExtraData: []byte("unwanted")}), errExtraDataDenied)
+	})
+
+	t.Run("Invalid regexp", func(t *testing.T) {
+		_, err := NewExtraDataFilter("(", "")
+		require.Error(t, err)
+		_, err = NewExtraDataFilter("", "[")
+		require.Error(t, err)
+	})
+}
+
+func TestValueBidFilters(t *testing.T) {
+	relay := mock.NewRelay(t).RelayEntry
+	bid := &BidCandidate{Relay: relay, Value: big.NewInt(1000)}
+
+	t.Run("Max bid", func(t *testing.T) {
+		require.NoError(t, MaxBidFilter{MaxBid: big.NewInt(1000)}.FilterBid(GetHeaderRequest{}, bid))
+		require.ErrorIs(t, MaxBidFilter{MaxBid: big.NewInt(999)}.FilterBid(GetHeaderRequest{}, bid), errBidAboveMaxBid)
+	})
+
+	t.Run("Min bid", func(t *testing.T) {
+		require.NoError(t, minBidFilter{minBid: types.IntToU256(1000)}.FilterBid(GetHeaderRequest{}, bid))
+		require.ErrorIs(t, minBidFilter{minBid: types.IntToU256(1001)}.FilterBid(GetHeaderRequest{}, bid), errBidBelowMinBid)
+
+		relayBid := *bid
+		relayMinBid := types.IntToU256(1001)
+		relayBid.Relay.MinBid = &relayMinBid
+		require.ErrorIs(t, minBidFilter{}.FilterBid(GetHeaderRequest{}, &relayBid), errBidBelowMinBid)
+	})
+}
+
+func TestGetHeaderBidFilters(t *testing.T) {
+	path := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+	newBackend := func(t *testing.T, filters ...BidFilter) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			20000,
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+		backend.boost.bidFilters = filters
+		return backend
+	}
+
+	t.Run("Bid accepted by the filters", func(t *testing.T) {
+		backend := newBackend(t, MaxBidFilter{MaxBid: big.NewInt(20000)})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bid above max bid", func(t *testing.T) {
+		backend := newBackend(t, MaxBidFilter{MaxBid: big.NewInt(19999)})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.InDelta(t, 1, testutil.ToFloat64(bidsFilteredTotal.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), "max-bid")), 0)
+	})
+
+	t.Run("Bid without builder tag", func(t *testing.T) {
+		filter, err := NewExtraDataFilter("^builder", "")
+		require.NoError(t, err)
+		backend := newBackend(t, filter)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Custom filter", func(t *testing.T) {
+		var filtered *BidCandidate
+		backend := newBackend(t, NewBidFilter("custom", func(req GetHeaderRequest, bid *BidCandidate) error {
+			require.Equal(t, uint64(1), req.Slot)
+			filtered = bid
+			return errors.New("refused") //nolint:err113
+		}))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.NotNil(t, filtered)
+		require.Equal(t, big.NewInt(20000), filtered.Value)
+		require.InDelta(t, 1, testutil.ToFloat64(bidsFilteredTotal.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), "custom")), 0)
+	})
+}
+
+func TestHeaderBidFilters(t *testing.T) {
+	bid := func(header bidHeaderFields) *BidCandidate {
+		return &BidCandidate{bidInfo: bidInfo{gasLimit: 30_000_000, header: header}}
+	}
+	valid := bidHeaderFields{
+		timestamp:     1000 + 2*config.SlotTimeSec,
+		baseFeePerGas: uint256.NewInt(7),
+		gasUsed:       15_000_000,
+		blobGasUsed:   2 * params.BlobTxBlobGasPerBlob,
+		numBlobs:      2,
+	}
+
+	t.Run("Timestamp", func(t *testing.T) {
+		filter := timestampFilter{genesisTime: 1000}
+		require.NoError(t, filter.FilterBid(GetHeaderRequest{Slot: 2}, bid(valid)))
+		require.ErrorIs(t, filter.FilterBid(GetHeaderRequest{Slot: 3}, bid(valid)), errBidTimestampMismatch)
+
+		// Not checked without a genesis time
+		require.NoError(t, timestampFilter{}.FilterBid(GetHeaderRequest{Slot: 3}, bid(valid)))
+	})
+
+	t.Run("Header fields", func(t *testing.T) {
+		require.NoError(t, headerFieldsFilter{}.FilterBid(GetHeaderRequest{}, bid(valid)))
+
+		header := valid
+		header.baseFeePerGas = uint256.NewInt(0)
+		require.ErrorIs(t, headerFieldsFilter{}.FilterBid(GetHeaderRequest{}, bid(header)), errBidZeroBaseFee)
+
+		header = valid
+		header.gasUsed = 30_000_001
+		require.ErrorIs(t, headerFieldsFilter{}.FilterBid(GetHeaderRequest{}, bid(header)), errBidGasUsedAboveLimit)
+
+		header = valid
+		header.blobGasUsed = 2*params.BlobTxBlobGasPerBlob + 1
+		require.ErrorIs(t, headerFieldsFilter{}.FilterBid(GetHeaderRequest{}, bid(header)), errBidBlobGasUsed)
+
+		header = valid
+		header.numBlobs = 3
+		require.ErrorIs(t, headerFieldsFilter{}.FilterBid(GetHeaderRequest{}, bid(header)), errBidBlobGasUsed)
+	})
+}
+
+func TestGetHeaderBlockNumbers(t *testing.T) {
+	parentHash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	path := getHeaderPath(1, mock.HexToHash(parentHash), mock.HexToPubkey(pubkey))
+
+	// newBackend returns a backend whose relays bid on blocks with the given block numbers, with increasing values
+	newBackend := func(t *testing.T, blockNumbers ...uint64) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, len(blockNumbers), time.Second)
+		for i, blockNumber := range blockNumbers {
+			blockHash := fmt.Sprintf("0xa%d8385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7", i)
+			response := backend.relays[i].MakeGetHeaderResponse(uint64(13000+i), blockHash, parentHash, pubkey, spec.DataVersionDeneb)
+			response.Deneb.Message.Header.BlockNumber = blockNumber
+			backend.relays[i].SignGetHeaderResponse(response)
+			backend.relays[i].GetHeaderResponse = response
+		}
+		return backend
+	}
+	bidBlockNumber := func(t *testing.T, rr *httptest.ResponseRecorder) uint64 {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		blockNumber, err := resp.BlockNumber()
+		require.NoError(t, err)
+		return blockNumber
+	}
+
+	t.Run("Block number sent by most relays", func(t *testing.T) {
+		backend := newBackend(t, 100, 100, 101)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint64(100), bidBlockNumber(t, rr))
+		require.InDelta(t, 1, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relayLabel(backend.relays[2].RelayEntry), methodGetHeader, errorClassInvalid)), 0)
+	})
+
+	t.Run("Relays disagreeing", func(t *testing.T) {
+		backend := newBackend(t, 100, 101)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Parent block known", func(t *testing.T) {
+		backend := newBackend(t, 100, 100, 101)
+		backend.boost.bids.put(bidRespKey{slot: 0, blockHash: parentHash}, bidResp{bidInfo: bidInfo{blockNumber: 100}})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint64(101), bidBlockNumber(t, rr))
+	})
+}
+
+func TestGetHeaderTimestamp(t *testing.T) {
+	path := getHeaderPath(2, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.genesisTime = 1000
+
+	// The relay sends a header for slot 1
+	backend.setSlot(1)
+	rr := backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	require.InDelta(t, 1, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relayLabel(backend.relays[0].RelayEntry), methodGetHeader, errorClassInvalid)), 0)
+
+	backend.setSlot(2)
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Without a genesis time, the timestamp is not checked
+	backend = newTestBackend(t, 1, time.Second)
+	backend.setSlot(1)
+	rr = backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+// Names of the bid selectors shipped with mev-boost, see NewBidSelector
+const (
+	BidSelectorDefault             = "default"
+	BidSelectorRelayPriority       = "relay-priority"
+	BidSelectorReliabilityWeighted = "reliability-weighted"
+)
+
+var errUnknownBidSelector = errors.New("unknown bid selector")
+
+// BidCandidate is a bid received from a relay for a getHeader request. Bid selectors only get the bids accepted
+// by the bid filters.
+type BidCandidate struct {
+	Bid        *builderSpec.VersionedSignedBuilderBid
+	BlockHash  phase0.Hash32
+	ExtraData  []byte
+	ReceivedAt time.Time
+
+	// Value is the value of the bid, AdjustedValue the value with the relay's multiplier and adjustment applied
+	Value         *big.Int
+	AdjustedValue *big.Int
+
+	// Relay is the relay which sent the bid, RelayPriority its position in the configured relays (0 first) and
+	// RelayHealth its health from the status checks, unset if not checked yet
+	Relay         types.RelayEntry
+	RelayPriority int
+	RelayHealth   RelayHealth
+
+	bidInfo bidInfo
+}
+
+// BidSelector selects the bid returned to the beacon node among all bids received for a getHeader request, eg.
+// all bids of all polls with timing games. SelectBid returns the index of the winning candidate, or -1 to return
+// no bid. Candidates are in the order they were received.
+type BidSelector interface {
+	SelectBid(candidates []BidCandidate) int
+}
+
+// NewBidSelector returns the bid selector shipped with mev-boost with the given name
+func NewBidSelector(name string) (BidSelector, error) {
+	switch name {
+	case BidSelectorDefault, "":
+		return DefaultBidSelector{}, nil
+	case BidSelectorRelayPriority:
+		return RelayPriorityBidSelector{}, nil
+	case BidSelectorReliabilityWeighted:
+		return ReliabilityWeightedBidSelector{}, nil
+	}
+	return nil, errUnknownBidSelector
+}
+
+// DefaultBidSelector selects the bid with the highest adjusted value, ties broken by the lexically smallest block
+// hash, then by the earliest bid
+type DefaultBidSelector struct{}
+
+func (DefaultBidSelector) SelectBid(candidates []BidCandidate) int {
+	return selectHighestBid(candidates, func(c *BidCandidate) *big.Int { return c.AdjustedValue })
+}
+
+// RelayPriorityBidSelector selects the bid of the relay configured first among the relays which sent a bid, and
+// among its bids the one the default selector would
+type RelayPriorityBidSelector struct{}
+
+func (RelayPriorityBidSelector) SelectBid(candidates []BidCandidate) int {
+	best := -1
+	for i := range candidates {
+		if best == -1 || candidates[i].RelayPriority < candidates[best].RelayPriority {
+			best = i
+		}
+	}
+	if best == -1 {
+		return -1
+	}
+
+	priority := candidates[best].RelayPriority
+	return selectHighestBid(candidates, func(c *BidCandidate) *big.Int {
+		if c.RelayPriority != priority {
+			return nil
+		}
+		return c.AdjustedValue
+	})
+}
+
+// ReliabilityWeightedBidSelector selects bids like the default selector, with the adjusted values divided by one
+// plus the number of consecutive failed status checks of the relay
+type ReliabilityWeightedBidSelector struct{}
+
+func (ReliabilityWeightedBidSelector) SelectBid(candidates []BidCandidate) int {
+	return selectHighestBid(candidates, func(c *BidCandidate) *big.Int {
+		if c.RelayHealth.ConsecutiveFailures == 0 {
+			return c.AdjustedValue
+		}
+		return new(big.Int).Div(c.AdjustedValue, big.NewInt(int64(c.RelayHealth.ConsecutiveFailures)+1))
+	})
+}
+
+// selectHighestBid returns the index of the candidate with the highest value, ties broken by the lexically smallest
+// block hash then by the earliest candidate. Candidates with a nil value are skipped.
+func selectHighestBid(candidates []BidCandidate, value func(c *BidCandidate) *big.Int) int {
+	best := -1
+	var bestValue *big.Int
+	for i := range candidates {
+		v := value(&candidates[i])
+		if v == nil {
+			continue
+		}
+		if best != -1 {
+			valueDiff := v.Cmp(bestValue)
+			if valueDiff == -1 { // less profitable than the best bid so far
+				continue
+			} else if valueDiff == 0 && candidates[i].BlockHash.String() >= candidates[best].BlockHash.String() {
+				continue
+			}
+		}
+		best, bestValue = i, v
+	}
+	return best
+}
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func testBidCandidate(value int64, blockHash byte, priority, failures int) BidCandidate {
+	return BidCandidate{
+		BlockHash:     phase0.Hash32{blockHash},
+		Value:         big.NewInt(value),
+		AdjustedValue: big.NewInt(value),
+		RelayPriority: priority,
+		RelayHealth:   RelayHealth{ConsecutiveFailures: failures},
+	}
+}
+
+func TestBidSelectors(t *testing.T) {
+	candidates := []BidCandidate{
+		testBidCandidate(100, 0x03, 2, 0),
+		testBidCandidate(300, 0x02, 1, 2),
+		testBidCandidate(300, 0x01, 1, 2),
+		testBidCandidate(120, 0x04, 0, 0),
+		testBidCandidate(200, 0x05, 0, 0),
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		require.Equal(t, -1, DefaultBidSelector{}.SelectBid(nil))
+		require.Equal(t, 2, DefaultBidSelector{}.SelectBid(candidates))
+	})
+
+	t.Run("Default uses the adjusted value", func(t *testing.T) {
+		adjusted := slices.Clone(candidates)
+		adjusted[0].AdjustedValue = big.NewInt(1000)
+		require.Equal(t, 0, DefaultBidSelector{}.SelectBid(adjusted))
+	})
+
+	t.Run("Relay priority", func(t *testing.T) {
+		require.Equal(t, -1, RelayPriorityBidSelector{}.SelectBid(nil))
+		require.Equal(t, 4, RelayPriorityBidSelector{}.SelectBid(candidates))
+	})
+
+	t.Run("Reliability weighted", func(t *testing.T) {
+		require.Equal(t, -1, ReliabilityWeightedBidSelector{}.SelectBid(nil))
+		require.Equal(t, 4, ReliabilityWeightedBidSelector{}.SelectBid(candidates))
+	})
+
+	t.Run("By name", func(t *testing.T) {
+		for name, expected := range map[string]BidSelector{
+			"":                             DefaultBidSelector{},
+			BidSelectorDefault:             DefaultBidSelector{},
+			BidSelectorRelayPriority:       RelayPriorityBidSelector{},
+			BidSelectorReliabilityWeighted: ReliabilityWeightedBidSelector{},
+		} {
+			selector, err := NewBidSelector(name)
+			require.NoError(t, err)
+			require.Equal(t, expected, selector)
+		}
+		_, err := NewBidSelector("highest")
+		require.ErrorIs(t, err, errUnknownBidSelector)
+	})
+}
+
+type noBidSelector struct{}
+
+func (noBidSelector) SelectBid([]BidCandidate) int { return -1 }
+
+func TestGetHeaderBidSelector(t *testing.T) {
+	path := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		for i, value := range []uint64{13000, 14000} {
+			backend.relays[i].GetHeaderResponse = backend.relays[i].MakeGetHeaderResponse(
+				value,
+				"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+				"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+				"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+				spec.DataVersionDeneb,
+			)
+		}
+		return backend
+	}
+
+	t.Run("Relay priority selects the bid of the first relay", func(t *testing.T) {
+		backend := newBackend(t)
+		backend.boost.bidSelector = RelayPriorityBidSelector{}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		value, err := resp.Value()
+		require.NoError(t, err)
+		require.Equal(t, uint256.NewInt(13000), value)
+	})
+
+	t.Run("Custom selector returning no bid", func(t *testing.T) {
+		backend := newBackend(t)
+		backend.boost.bidSelector = noBidSelector{}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+}
@@ -0,0 +1,224 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+// DefaultBidStoreRetentionSlots is the number of slots before the latest stored bid for which bids are kept on disk
+const DefaultBidStoreRetentionSlots = 64
+
+// bidRecord is a bid returned for getHeader, as stored on disk
+type bidRecord struct {
+	Slot       uint64                                 `json:"slot"`
+	BlockHash  string                                 `json:"block_hash"`
+	ReceivedAt time.Time                              `json:"received_at"`
+	Relays     []string                               `json:"relays"`
+	Bid        *builderSpec.VersionedSignedBuilderBid `json:"bid"`
+}
+
+// bidStore keeps the bids returned for getHeader on disk, keyed by slot and block hash, so that getPayload still
+// knows which relays offered a bid after a restart. Bids are appended to a file, one JSON record per line, and the
+// file is rewritten without the bids older than retentionSlots once they are the majority.
+type bidStore struct {
+	path           string
+	retentionSlots uint64
+
+	file       *os.File
+	bids       map[bidRespKey]bidRecord
+	latestSlot uint64
+	stale      int // records in the file which are not in bids anymore
+	mu         sync.Mutex
+}
+
+// openBidStore opens the bid store saved to path, creating it if needed
+func openBidStore(path string, retentionSlots uint64) (*bidStore, error) {
+	s := &bidStore{
+		path:           path,
+		retentionSlots: retentionSlots,
+		bids:           make(map[bidRespKey]bidRecord),
+	}
+
+	file, err := os.Open(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to open bid store: %w", err)
+	} else if err == nil {
+		err = s.load(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bid store: %w", err)
+		}
+	}
+
+	// Start with a file holding the retained bids only
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the records of a bid store file. A record cut short by a crash while appending is skipped.
+func (s *bidStore) load(r io.Reader) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			record := bidRecord{}
+			if decodeErr := json.Unmarshal(line, &record); decodeErr == nil {
+				s.add(record)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// add adds a record to the bids, and forgets the bids out of the retention
+func (s *bidStore) add(record bidRecord) {
+	key := bidRespKey{slot: record.Slot, blockHash: record.BlockHash}
+	if _, ok := s.bids[key]; ok {
+		s.stale++
+	}
+	s.bids[key] = record
+
+	if record.Slot <= s.latestSlot {
+		return
+	}
+	s.latestSlot = record.Slot
+	for key := range s.bids {
+		if key.slot+s.retentionSlots < s.latestSlot {
+			delete(s.bids, key)
+			s.stale++
+		}
+	}
+}
+
+// put stores a bid
+func (s *bidStore) put(record bidRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write bid store: %w", err)
+	}
+	s.add(record)
+	if s.stale > len(s.bids) {
+		return s.compact()
+	}
+	return nil
+}
+
+// get returns the stored bid of a slot and block hash, if any
+func (s *bidStore) get(key bidRespKey) (bidRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.bids[key]
+	return record, ok
+}
+
+// compact rewrites the file with the retained bids only, and reopens it for appending
+func (s *bidStore) compact() error {
+	records := make([]bidRecord, 0, len(s.bids))
+	for _, record := range s.bids {
+		records = append(records, record)
+	}
+	slices.SortFunc(records, func(a, b bidRecord) int {
+		return a.ReceivedAt.Compare(b.ReceivedAt)
+	})
+
+	var data []byte
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to write bid store: %w", err)
+	}
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open bid store: %w", err)
+	}
+	s.file = file
+	s.stale = 0
+	return nil
+}
+
+// Close closes the bid store file
+func (s *bidStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// newBidRecord returns the record of a bid returned for getHeader
+func newBidRecord(key bidRespKey, bid bidResp) bidRecord {
+	return bidRecord{
+		Slot:       key.slot,
+		BlockHash:  key.blockHash,
+		ReceivedAt: bid.t,
+		Relays:     types.RelayEntriesToStrings(bid.relays),
+		Bid:        &bid.response,
+	}
+}
+
+// storedBid returns a bid of the bid store, with its relays taken from the configured relays when they are still
+// configured
+func (m *BoostService) storedBid(key bidRespKey, configured []types.RelayEntry) (bidResp, bool) {
+	if m.bidStore == nil {
+		return bidResp{}, false
+	}
+	record, ok := m.bidStore.get(key)
+	if !ok || record.Bid == nil {
+		return bidResp{}, false
+	}
+	bidInfo, err := parseBidInfo(record.Bid)
+	if err != nil {
+		return bidResp{}, false
+	}
+
+	bid := bidResp{t: record.ReceivedAt, response: *record.Bid, bidInfo: bidInfo}
+	for _, url := range record.Relays {
+		idx := slices.IndexFunc(configured, func(relay types.RelayEntry) bool { return relay.String() == url })
+		if idx >= 0 {
+			bid.relays = append(bid.relays, configured[idx])
+			continue
+		}
+		relay, err := types.NewRelayEntry(url)
+		if err != nil {
+			continue
+		}
+		bid.relays = append(bid.relays, relay)
+	}
+	return bid, true
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testBidRecord(t *testing.T, slot uint64, blockHash string) bidRecord {
+	t.Helper()
+	relay := mock.NewRelay(t)
+	bid := relay.MakeGetHeaderResponse(12345, blockHash, blockHash, "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249", spec.DataVersionDeneb)
+	return bidRecord{
+		Slot:       slot,
+		BlockHash:  blockHash,
+		ReceivedAt: time.Now().UTC().Truncate(time.Millisecond),
+		Relays:     []string{relay.RelayEntry.String()},
+		Bid:        bid,
+	}
+}
+
+func TestBidStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bids.jsonl")
+	hash1 := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	hash2 := "0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+
+	store, err := openBidStore(path, 2)
+	require.NoError(t, err)
+	record := testBidRecord(t, 100, hash1)
+	require.NoError(t, store.put(record))
+	require.NoError(t, store.put(testBidRecord(t, 100, hash2)))
+	_, ok := store.get(bidRespKey{slot: 101, blockHash: hash1})
+	require.False(t, ok)
+
+	// The bids survive a restart
+	require.NoError(t, store.Close())
+	store, err = openBidStore(path, 2)
+	require.NoError(t, err)
+	stored, ok := store.get(bidRespKey{slot: 100, blockHash: hash1})
+	require.True(t, ok)
+	require.Equal(t, record.Relays, stored.Relays)
+	require.True(t, record.ReceivedAt.Equal(stored.ReceivedAt))
+	require.Equal(t, record.Bid.Deneb.Message.Header.BlockHash, stored.Bid.Deneb.Message.Header.BlockHash)
+
+	// Bids out of the retention are forgotten, and removed from the file once they are the majority
+	require.NoError(t, store.put(testBidRecord(t, 102, hash1)))
+	_, ok = store.get(bidRespKey{slot: 100, blockHash: hash1})
+	require.True(t, ok)
+	require.NoError(t, store.put(testBidRecord(t, 103, hash1)))
+	_, ok = store.get(bidRespKey{slot: 100, blockHash: hash1})
+	require.False(t, ok)
+	require.Len(t, store.bids, 2)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 4, bytes.Count(data, []byte("\n")))
+	require.NoError(t, store.put(testBidRecord(t, 105, hash1)))
+	require.Len(t, store.bids, 2)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, 2, bytes.Count(data, []byte("\n")))
+
+	// A record cut short by a crash is skipped
+	require.NoError(t, store.Close())
+	require.NoError(t, os.WriteFile(path, append(data, data[:len(data)/3]...), 0o600))
+	store, err = openBidStore(path, 2)
+	require.NoError(t, err)
+	require.Len(t, store.bids, 2)
+	require.NoError(t, store.Close())
+}
+
+func TestGetPayloadBidStore(t *testing.T) {
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
+	slot := uint64(signedBlindedBlock.Message.Slot)
+	header := signedBlindedBlock.Message.Body.ExecutionPayloadHeader
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	path := filepath.Join(t.TempDir(), "bids.jsonl")
+
+	// getHeader, the bid is only offered by relay 0
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.bidStore, err = openBidStore(path, DefaultBidStoreRetentionSlots)
+	require.NoError(t, err)
+	backend.setSlot(slot)
+	backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+		12345, header.BlockHash.String(), header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+	rr := backend.request(t, http.MethodGet, getHeaderPath(slot, header.ParentHash, mock.HexToPubkey(pubkey)), nil)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// Restart, the bid is only left in the bid store
+	require.NoError(t, backend.boost.Close())
+	backend.boost.bids = newBidCache(context.Background(), 0, 0, bidCacheRetentionSlots, bidCacheMaxBids)
+	backend.boost.bidStore, err = openBidStore(path, DefaultBidStoreRetentionSlots)
+	require.NoError(t, err)
+
+	// getPayload is only sent to relay 0
+	backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionDeneb,
+		Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBlock),
+	}
+	getPayloadPath := "/eth/v1/builder/blinded_blocks"
+	rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+	require.Equal(t, 0, backend.relays[1].GetRequestCount(getPayloadPath))
+
+	// Withholding is attributed to relay 0
+	backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	withheld := testutil.ToFloat64(payloadWithheldTotal.WithLabelValues(relayLabel(backend.relays[0].RelayEntry)))
+	rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+	require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+	require.InDelta(t, withheld+1, testutil.ToFloat64(payloadWithheldTotal.WithLabelValues(relayLabel(backend.relays[0].RelayEntry))), 0)
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+)
+
+var errInvalidBlobProof = errors.New("invalid blob KZG proof")
+
+// verifyBlobsBundle verifies the KZG proof of each blob of a bundle against its commitment, in parallel. The number
+// of blobs, commitments and proofs must have been checked to match already.
+func verifyBlobsBundle(bundle *builderApiDeneb.BlobsBundle) error {
+	errs := make([]error, len(bundle.Blobs))
+	var wg sync.WaitGroup
+	for i := range bundle.Blobs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			blob := (*kzg4844.Blob)(&bundle.Blobs[i])
+			if err := kzg4844.VerifyBlobProof(blob, kzg4844.Commitment(bundle.Commitments[i]), kzg4844.Proof(bundle.Proofs[i])); err != nil {
+				errs[i] = fmt.Errorf("%w: blob %d: %w", errInvalidBlobProof, i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
+	"github.com/stretchr/testify/require"
+)
+
+// testBlobsBundle returns a bundle of valid blobs, with their commitments and proofs
+func testBlobsBundle(t *testing.T, numBlobs int) *builderApiDeneb.BlobsBundle {
+	t.Helper()
+	bundle := &builderApiDeneb.BlobsBundle{
+		Blobs:       make([]deneb.Blob, numBlobs),
+		Commitments: make([]deneb.KZGCommitment, numBlobs),
+		Proofs:      make([]deneb.KZGProof, numBlobs),
+	}
+	for i := range bundle.Blobs {
+		// Each 32 bytes field element must be below the BLS modulus, so leave the first byte empty
+		for j := 0; j < 64; j++ {
+			bundle.Blobs[i][j*32+31] = byte(i + j + 1)
+		}
+		blob := (*kzg4844.Blob)(&bundle.Blobs[i])
+		commitment, err := kzg4844.BlobToCommitment(blob)
+		require.NoError(t, err)
+		proof, err := kzg4844.ComputeBlobProof(blob, commitment)
+		require.NoError(t, err)
+		bundle.Commitments[i] = deneb.KZGCommitment(commitment)
+		bundle.Proofs[i] = deneb.KZGProof(proof)
+	}
+	return bundle
+}
+
+func TestVerifyBlobsBundle(t *testing.T) {
+	bundle := testBlobsBundle(t, 3)
+	require.NoError(t, verifyBlobsBundle(bundle))
+	require.NoError(t, verifyBlobsBundle(&builderApiDeneb.BlobsBundle{}))
+
+	// Proofs of other blobs
+	bundle.Proofs[0], bundle.Proofs[1] = bundle.Proofs[1], bundle.Proofs[0]
+	require.ErrorIs(t, verifyBlobsBundle(bundle), errInvalidBlobProof)
+
+	// Blob changed after the commitment
+	bundle = testBlobsBundle(t, 2)
+	bundle.Blobs[1][63]++
+	err := verifyBlobsBundle(bundle)
+	require.ErrorIs(t, err, errInvalidBlobProof)
+	require.ErrorContains(t, err, "blob 1")
+}
+
+func TestGetPayloadVerifyBlobs(t *testing.T) {
+	path := "/eth/v1/builder/blinded_blocks"
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	bundle := testBlobsBundle(t, 2)
+	signedBlindedBlock.Message.Body.BlobKZGCommitments = bundle.Commitments
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
+
+	// The first relay returns blobs which don't match the commitments, the second one valid blobs
+	newBackend := func(t *testing.T, verifyBlobs bool) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.verifyBlobs = verifyBlobs
+		invalid := blindedBlockContentsToPayloadDeneb(signedBlindedBlock)
+		invalid.BlobsBundle = testBlobsBundle(t, 2)
+		invalid.BlobsBundle.Blobs[0][31]++
+		backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: invalid}
+		valid := blindedBlockContentsToPayloadDeneb(signedBlindedBlock)
+		valid.BlobsBundle = bundle
+		backend.relays[1].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: valid}
+		backend.relays[1].ResponseDelay = 100 * time.Millisecond
+		return backend
+	}
+	blob := func(t *testing.T, rr *httptest.ResponseRecorder) deneb.Blob {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		resp := new(builderApi.VersionedSubmitBlindedBlockResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		return resp.Deneb.BlobsBundle.Blobs[0]
+	}
+
+	t.Run("Blobs not verified", func(t *testing.T) {
+		backend := newBackend(t, false)
+		rr := backend.request(t, http.MethodPost, path, signedBlindedBlock)
+		require.NotEqual(t, bundle.Blobs[0], blob(t, rr))
+	})
+
+	t.Run("Invalid blobs skipped", func(t *testing.T) {
+		backend := newBackend(t, true)
+		rr := backend.request(t, http.MethodPost, path, signedBlindedBlock)
+		require.Equal(t, bundle.Blobs[0], blob(t, rr))
+	})
+}
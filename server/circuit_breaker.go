@@ -0,0 +1,151 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// breakerState is the state of a relay circuit breaker
+type breakerState int
+
+const (
+	// breakerClosed lets getHeader requests through to the relay
+	breakerClosed breakerState = iota
+	// breakerOpen skips the relay for getHeader, until the cooldown elapsed or a status check succeeded
+	breakerOpen
+	// breakerHalfOpen lets a single getHeader request through on trial, the next success closes the breaker and the
+	// next failure opens it again
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerClosed:
+		return "closed"
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+var relayCircuitBreakerGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "relay_circuit_breaker_state",
+	Help:      "State of the relay circuit breaker: 0 closed, 1 open, 2 half-open",
+}, []string{"relay"})
+
+func init() {
+	prometheus.MustRegister(relayCircuitBreakerGauge)
+}
+
+type relayBreaker struct {
+	state          breakerState
+	failures       int
+	openedAt       time.Time
+	trialStartedAt time.Time // start of the trial request in flight while half-open, if any
+}
+
+// relayCircuitBreakers keeps a circuit breaker per relay, keyed by relay URL. A relay is sidelined from getHeader
+// after threshold consecutive getHeader or status failures, and tried again after cooldown or once a status
+// check succeeds. A threshold of 0 disables the breakers.
+type relayCircuitBreakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	breakers map[string]*relayBreaker
+	mu       sync.Mutex
+}
+
+func newRelayCircuitBreakers(threshold int, cooldown time.Duration) *relayCircuitBreakers {
+	return &relayCircuitBreakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  make(map[string]*relayBreaker),
+	}
+}
+
+func (c *relayCircuitBreakers) breaker(relay types.RelayEntry) *relayBreaker {
+	b, ok := c.breakers[relay.String()]
+	if !ok {
+		b = &relayBreaker{}
+		c.breakers[relay.String()] = b
+	}
+	return b
+}
+
+func (c *relayCircuitBreakers) setState(relay types.RelayEntry, b *relayBreaker, state breakerState) {
+	b.state = state
+	b.trialStartedAt = time.Time{}
+	if state == breakerOpen {
+		b.openedAt = time.Now()
+	}
+	relayCircuitBreakerGauge.WithLabelValues(relayLabel(relay)).Set(float64(state))
+}
+
+// allow returns whether a getHeader request may be sent to the relay. An open breaker turns half-open once the
+// cooldown has elapsed. A half-open breaker lets one request through on trial, and another one only if the trial
+// request got no result within the cooldown.
+func (c *relayCircuitBreakers) allow(relay types.RelayEntry) bool {
+	if c.threshold <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.breaker(relay)
+	if b.state == breakerOpen && time.Since(b.openedAt) >= c.cooldown {
+		c.setState(relay, b, breakerHalfOpen)
+	}
+	switch b.state {
+	case breakerOpen:
+		return false
+	case breakerHalfOpen:
+		if !b.trialStartedAt.IsZero() && time.Since(b.trialStartedAt) < c.cooldown {
+			return false
+		}
+		b.trialStartedAt = time.Now()
+	case breakerClosed:
+	}
+	return true
+}
+
+// record updates the breaker of the relay with the result of a getHeader or status request
+func (c *relayCircuitBreakers) record(relay types.RelayEntry, success bool) {
+	if c.threshold <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b := c.breaker(relay)
+	b.trialStartedAt = time.Time{}
+	if success {
+		b.failures = 0
+		switch b.state {
+		case breakerOpen:
+			c.setState(relay, b, breakerHalfOpen)
+		case breakerHalfOpen:
+			c.setState(relay, b, breakerClosed)
+		case breakerClosed:
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= c.threshold {
+		c.setState(relay, b, breakerOpen)
+	}
+}
+
+// state returns the breaker state of the relay
+func (c *relayCircuitBreakers) state(relay types.RelayEntry) breakerState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if b, ok := c.breakers[relay.String()]; ok {
+		return b.state
+	}
+	return breakerClosed
+}
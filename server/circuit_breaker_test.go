@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayCircuitBreakers(t *testing.T) {
+	relay := mock.NewRelay(t).RelayEntry
+
+	t.Run("Disabled with a zero threshold", func(t *testing.T) {
+		breakers := newRelayCircuitBreakers(0, time.Hour)
+		for i := 0; i < 10; i++ {
+			breakers.record(relay, false)
+		}
+		require.True(t, breakers.allow(relay))
+		require.Equal(t, breakerClosed, breakers.state(relay))
+	})
+
+	t.Run("Opens after consecutive failures", func(t *testing.T) {
+		breakers := newRelayCircuitBreakers(3, time.Hour)
+		breakers.record(relay, false)
+		breakers.record(relay, false)
+		breakers.record(relay, true)
+		breakers.record(relay, false)
+		breakers.record(relay, false)
+		require.True(t, breakers.allow(relay))
+		breakers.record(relay, false)
+		require.Equal(t, breakerOpen, breakers.state(relay))
+		require.False(t, breakers.allow(relay))
+	})
+
+	t.Run("Half-open after a successful probe", func(t *testing.T) {
+		breakers := newRelayCircuitBreakers(1, time.Hour)
+		breakers.record(relay, false)
+		require.False(t, breakers.allow(relay))
+
+		breakers.record(relay, true)
+		require.Equal(t, breakerHalfOpen, breakers.state(relay))
+		require.True(t, breakers.allow(relay))
+
+		// A single trial request at a time
+		require.False(t, breakers.allow(relay))
+
+		breakers.record(relay, true)
+		require.Equal(t, breakerClosed, breakers.state(relay))
+		require.True(t, breakers.allow(relay))
+		require.True(t, breakers.allow(relay))
+	})
+
+	t.Run("Half-open after the cooldown, and open again on failure", func(t *testing.T) {
+		breakers := newRelayCircuitBreakers(2, 0)
+		breakers.record(relay, false)
+		breakers.record(relay, false)
+		require.Equal(t, breakerOpen, breakers.state(relay))
+
+		require.True(t, breakers.allow(relay))
+		require.Equal(t, breakerHalfOpen, breakers.state(relay))
+
+		breakers.record(relay, false)
+		require.Equal(t, breakerOpen, breakers.state(relay))
+	})
+}
+
+func TestGetHeaderCircuitBreaker(t *testing.T) {
+	path := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+
+	backend := newTestBackend(t, 2, time.Second)
+	backend.boost.relayBreakers = newRelayCircuitBreakers(2, time.Hour)
+	failingRelay := backend.relays[1]
+	failingRelay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	// The failing relay is skipped once the breaker is open
+	for i := 0; i < 3; i++ {
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	require.Equal(t, 3, backend.relays[0].GetRequestCount(path))
+	require.Equal(t, 2, failingRelay.GetRequestCount(path))
+	require.Equal(t, breakerOpen, backend.boost.relayBreakers.state(failingRelay.RelayEntry))
+
+	// A successful status check lets a getHeader request through on trial, opening the breaker again on failure
+	require.Equal(t, 2, backend.boost.CheckRelays(context.Background()))
+	require.Equal(t, breakerHalfOpen, backend.boost.relayBreakers.state(failingRelay.RelayEntry))
+	backend.request(t, http.MethodGet, path, nil)
+	backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, 3, failingRelay.GetRequestCount(path))
+	require.Equal(t, breakerOpen, backend.boost.relayBreakers.state(failingRelay.RelayEntry))
+
+	// The relay is back once it responds to a trial request
+	failingRelay.OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	backend.boost.relayBreakers.cooldown = 0
+	backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, breakerClosed, backend.boost.relayBreakers.state(failingRelay.RelayEntry))
+	backend.request(t, http.MethodGet, path, nil)
+	require.Equal(t, 5, failingRelay.GetRequestCount(path))
+}
+
+func TestGetPayloadCircuitBreaker(t *testing.T) {
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.boost.relayBreakers = newRelayCircuitBreakers(1, time.Hour)
+	backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionDeneb,
+		Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBlock),
+	}
+
+	// getPayload is sent to relays whatever the state of their breaker
+	backend.boost.relayBreakers.record(backend.relays[0].RelayEntry, false)
+	require.False(t, backend.boost.relayBreakers.allow(backend.relays[0].RelayEntry))
+
+	path := "/eth/v1/builder/blinded_blocks"
+	rr := backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+}
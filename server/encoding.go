@@ -0,0 +1,305 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderApiElectra "github.com/attestantio/go-builder-client/api/electra"
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	eth2Api "github.com/attestantio/go-eth2-client/api"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	eth2ApiV1Electra "github.com/attestantio/go-eth2-client/api/v1/electra"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/types"
+)
+
+const (
+	MediaTypeJSON        = "application/json"
+	MediaTypeOctetStream = "application/octet-stream"
+
+	// acceptSSZ is the Accept header sent to relays, preferring SSZ responses over JSON
+	acceptSSZ = "application/octet-stream;q=1.0,application/json;q=0.9"
+)
+
+var (
+	errNotAcceptable        = errors.New("no acceptable media type, supported are application/json and application/octet-stream")
+	errUnsupportedMediaType = errors.New("unsupported media type, supported are application/json and application/octet-stream")
+)
+
+// negotiateMediaType returns the response media type preferred in an Accept header, JSON if the header is empty or
+// accepts any type, and false if neither JSON nor SSZ is acceptable
+func negotiateMediaType(accept string) (string, bool) {
+	if accept == "" {
+		return MediaTypeJSON, true
+	}
+
+	bestType, bestQuality := "", 0.0
+	for _, entry := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(entry))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case MediaTypeJSON, MediaTypeOctetStream:
+		case "*/*", "application/*":
+			mediaType = MediaTypeJSON
+		default:
+			continue
+		}
+		// The first of equally preferred types wins
+		if quality > bestQuality {
+			bestType, bestQuality = mediaType, quality
+		}
+	}
+	return bestType, bestType != ""
+}
+
+// requestIsSSZ returns whether the request body is SSZ encoded according to its Content-Type header. JSON is
+// assumed if the header is not set.
+func requestIsSSZ(req *http.Request) (bool, error) {
+	contentType := req.Header.Get("Content-Type")
+	if contentType == "" {
+		return false, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s", errUnsupportedMediaType, contentType)
+	}
+	switch mediaType {
+	case MediaTypeJSON:
+		return false, nil
+	case MediaTypeOctetStream:
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: %s", errUnsupportedMediaType, contentType)
+	}
+}
+
+// signedBuilderBidSSZ returns the SSZ encoding of the version specific signed builder bid
+func signedBuilderBidSSZ(bid *builderSpec.VersionedSignedBuilderBid) ([]byte, error) {
+	switch bid.Version {
+	case spec.DataVersionDeneb:
+		return bid.Deneb.MarshalSSZ()
+	case spec.DataVersionElectra:
+		return bid.Electra.MarshalSSZ()
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, bid.Version)
+	}
+	return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, bid.Version)
+}
+
+// getPayloadResponseSSZ returns the SSZ encoding of the execution payload and blobs bundle of a getPayload response
+func getPayloadResponseSSZ(payload *builderApi.VersionedSubmitBlindedBlockResponse) ([]byte, error) {
+	bundle := getPayloadResponseBundle(payload)
+	if bundle == nil {
+		return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, payload.Version)
+	}
+	return bundle.MarshalSSZ()
+}
+
+// decodeSignedBlindedBeaconBlock decodes the JSON or SSZ signed blinded beacon block sent by the beacon node. The
+// version is taken from the Eth-Consensus-Version header if set, else from the fork schedule at the slot of the
+// block. Without either, the supported versions are tried from newest to oldest, as blocks of older forks lack
+// fields required by newer ones.
+func decodeSignedBlindedBeaconBlock(body []byte, sszEncoded bool, consensusVersion string, schedule *types.ForkSchedule) (*eth2Api.VersionedSignedBlindedBeaconBlock, error) {
+	if consensusVersion != "" {
+		version, err := types.ParseDataVersion(consensusVersion)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, consensusVersion)
+		}
+		return decodeSignedBlindedBeaconBlockVersion(body, sszEncoded, version)
+	}
+
+	if schedule != nil {
+		slot, err := signedBlindedBeaconBlockSlot(body, sszEncoded)
+		if err != nil {
+			return nil, err
+		}
+		return decodeSignedBlindedBeaconBlockVersion(body, sszEncoded, schedule.VersionAtSlot(slot))
+	}
+
+	var err error
+	for _, version := range []spec.DataVersion{spec.DataVersionElectra, spec.DataVersionDeneb} {
+		var block *eth2Api.VersionedSignedBlindedBeaconBlock
+		block, err = decodeSignedBlindedBeaconBlockVersion(body, sszEncoded, version)
+		if err == nil {
+			return block, nil
+		}
+	}
+	return nil, err
+}
+
+// signedBlindedBeaconBlockSlot returns the slot of a JSON or SSZ signed blinded beacon block of any version
+func signedBlindedBeaconBlockSlot(body []byte, sszEncoded bool) (uint64, error) {
+	if sszEncoded {
+		// The block starts with the offset of the message, which starts with the slot
+		if len(body) < 4 {
+			return 0, errInvalidSlot
+		}
+		offset := binary.LittleEndian.Uint32(body[:4])
+		if uint64(len(body)) < uint64(offset)+8 {
+			return 0, errInvalidSlot
+		}
+		return binary.LittleEndian.Uint64(body[offset : offset+8]), nil
+	}
+
+	block := struct {
+		Message struct {
+			Slot string `json:"slot"`
+		} `json:"message"`
+	}{}
+	if err := json.Unmarshal(body, &block); err != nil {
+		return 0, err
+	}
+	slot, err := strconv.ParseUint(block.Message.Slot, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", errInvalidSlot, err)
+	}
+	return slot, nil
+}
+
+func decodeSignedBlindedBeaconBlockVersion(body []byte, sszEncoded bool, version spec.DataVersion) (*eth2Api.VersionedSignedBlindedBeaconBlock, error) {
+	block := &eth2Api.VersionedSignedBlindedBeaconBlock{Version: version}
+	switch version {
+	case spec.DataVersionDeneb:
+		block.Deneb = new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+		if sszEncoded {
+			return block, block.Deneb.UnmarshalSSZ(body)
+		}
+		return block, DecodeJSON(bytes.NewReader(body), block.Deneb)
+	case spec.DataVersionElectra:
+		block.Electra = new(eth2ApiV1Electra.SignedBlindedBeaconBlock)
+		if sszEncoded {
+			return block, block.Electra.UnmarshalSSZ(body)
+		}
+		return block, DecodeJSON(bytes.NewReader(body), block.Electra)
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+	}
+	return nil, fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+}
+
+// sszMarshaler is implemented by the request payloads which can be sent to relays SSZ encoded
+type sszMarshaler interface {
+	MarshalSSZ() ([]byte, error)
+}
+
+// sszPayload marks a request payload to be sent SSZ encoded by SendHTTPRequest
+type sszPayload struct {
+	sszMarshaler
+}
+
+// versionedResponse is implemented by relay responses which can be decoded from SSZ, the version being given by
+// the Eth-Consensus-Version header of the response
+type versionedResponse interface {
+	unmarshalSSZ(consensusVersion string, data []byte) error
+	isSSZ() bool
+}
+
+// builderBidResponse is a getHeader response, decoded from JSON or versioned SSZ
+type builderBidResponse struct {
+	*builderSpec.VersionedSignedBuilderBid
+	sszEncoded bool
+}
+
+func (r *builderBidResponse) unmarshalSSZ(consensusVersion string, data []byte) error {
+	version, err := types.ParseDataVersion(consensusVersion)
+	if err != nil {
+		return fmt.Errorf("%w: %q", errUnsupportedVersion, consensusVersion)
+	}
+	switch version {
+	case spec.DataVersionDeneb:
+		r.Version, r.Deneb = version, new(builderApiDeneb.SignedBuilderBid)
+		r.sszEncoded = true
+		return r.Deneb.UnmarshalSSZ(data)
+	case spec.DataVersionElectra:
+		r.Version, r.Electra = version, new(builderApiElectra.SignedBuilderBid)
+		r.sszEncoded = true
+		return r.Electra.UnmarshalSSZ(data)
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+	}
+	return fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+}
+
+func (r *builderBidResponse) isSSZ() bool {
+	return r.sszEncoded
+}
+
+// submitBlindedBlockResponse is a getPayload response, decoded from JSON or versioned SSZ
+type submitBlindedBlockResponse struct {
+	*builderApi.VersionedSubmitBlindedBlockResponse
+	sszEncoded bool
+}
+
+func (r *submitBlindedBlockResponse) unmarshalSSZ(consensusVersion string, data []byte) error {
+	version, err := types.ParseDataVersion(consensusVersion)
+	if err != nil {
+		return fmt.Errorf("%w: %q", errUnsupportedVersion, consensusVersion)
+	}
+	bundle := new(builderApiDeneb.ExecutionPayloadAndBlobsBundle)
+	switch version {
+	case spec.DataVersionDeneb:
+		r.Version, r.Deneb = version, bundle
+		r.sszEncoded = true
+		return bundle.UnmarshalSSZ(data)
+	case spec.DataVersionElectra:
+		r.Version, r.Electra = version, bundle
+		r.sszEncoded = true
+		return bundle.UnmarshalSSZ(data)
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+	}
+	return fmt.Errorf("%w: %s", errUnsupportedVersion, version)
+}
+
+func (r *submitBlindedBlockResponse) isSSZ() bool {
+	return r.sszEncoded
+}
+
+// responseIsSSZ returns whether a relay response is SSZ encoded according to its Content-Type header
+func responseIsSSZ(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == MediaTypeOctetStream
+}
+
+// relaySSZSupport caches whether relays support SSZ, keyed by relay URL. Relays are asked for SSZ responses until
+// they reject them, and sent SSZ request bodies once they have responded with SSZ.
+type relaySSZSupport struct {
+	supported map[string]bool
+	mu        sync.RWMutex
+}
+
+// get returns whether the relay supports SSZ, and false as second value if this is not known yet
+func (s *relaySSZSupport) get(relay types.RelayEntry) (supported, known bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	supported, known = s.supported[relay.String()]
+	return supported, known
+}
+
+func (s *relaySSZSupport) set(relay types.RelayEntry, supported bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.supported == nil {
+		s.supported = make(map[string]bool)
+	}
+	s.supported[relay.String()] = supported
+}
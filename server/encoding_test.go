@@ -0,0 +1,143 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNegotiateMediaType(t *testing.T) {
+	testCases := []struct {
+		accept    string
+		expected  string
+		supported bool
+	}{
+		{accept: "", expected: MediaTypeJSON, supported: true},
+		{accept: "application/json", expected: MediaTypeJSON, supported: true},
+		{accept: "application/octet-stream", expected: MediaTypeOctetStream, supported: true},
+		{accept: "application/octet-stream;q=1.0,application/json;q=0.9", expected: MediaTypeOctetStream, supported: true},
+		{accept: "application/json;q=0.9, application/octet-stream", expected: MediaTypeOctetStream, supported: true},
+		{accept: "application/octet-stream;q=0.5,application/json", expected: MediaTypeJSON, supported: true},
+		{accept: "application/octet-stream,application/json", expected: MediaTypeOctetStream, supported: true},
+		{accept: "*/*", expected: MediaTypeJSON, supported: true},
+		{accept: "text/html,application/*;q=0.1", expected: MediaTypeJSON, supported: true},
+		{accept: "text/html", supported: false},
+		{accept: "application/octet-stream;q=0", supported: false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.accept, func(t *testing.T) {
+			mediaType, ok := negotiateMediaType(tt.accept)
+			require.Equal(t, tt.supported, ok)
+			require.Equal(t, tt.expected, mediaType)
+		})
+	}
+}
+
+func TestRequestIsSSZ(t *testing.T) {
+	req, err := http.NewRequest(http.MethodPost, "/", nil)
+	require.NoError(t, err)
+
+	sszEncoded, err := requestIsSSZ(req)
+	require.NoError(t, err)
+	require.False(t, sszEncoded)
+
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	sszEncoded, err = requestIsSSZ(req)
+	require.NoError(t, err)
+	require.False(t, sszEncoded)
+
+	req.Header.Set("Content-Type", MediaTypeOctetStream)
+	sszEncoded, err = requestIsSSZ(req)
+	require.NoError(t, err)
+	require.True(t, sszEncoded)
+
+	req.Header.Set("Content-Type", "text/plain")
+	_, err = requestIsSSZ(req)
+	require.ErrorIs(t, err, errUnsupportedMediaType)
+}
+
+func TestDecodeSignedBlindedBeaconBlock(t *testing.T) {
+	denebBlock, err := os.ReadFile("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	electraBlock, err := os.ReadFile("../testdata/signed-blinded-beacon-block-electra.json")
+	require.NoError(t, err)
+	denebSchedule, err := types.NewForkSchedule([]types.Fork{{Version: spec.DataVersionDeneb, Epoch: 0}})
+	require.NoError(t, err)
+	electraSchedule, err := types.NewForkSchedule([]types.Fork{
+		{Version: spec.DataVersionDeneb, Epoch: 0},
+		{Version: spec.DataVersionElectra, Epoch: 0},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		body             []byte
+		consensusVersion string
+		schedule         *types.ForkSchedule
+		expected         spec.DataVersion
+		expectedErr      error
+	}{
+		{name: "Deneb with version header", body: denebBlock, consensusVersion: "deneb", expected: spec.DataVersionDeneb},
+		{name: "Electra with version header", body: electraBlock, consensusVersion: "Electra", expected: spec.DataVersionElectra},
+		{name: "Deneb without version header", body: denebBlock, expected: spec.DataVersionDeneb},
+		{name: "Electra without version header", body: electraBlock, expected: spec.DataVersionElectra},
+		{name: "Deneb block with electra version header", body: denebBlock, consensusVersion: "electra"},
+		{name: "Unsupported version header", body: denebBlock, consensusVersion: "capella", expectedErr: errUnsupportedVersion},
+		{name: "Unknown version header", body: denebBlock, consensusVersion: "foo", expectedErr: errUnsupportedVersion},
+		{name: "Deneb from fork schedule", body: denebBlock, schedule: denebSchedule, expected: spec.DataVersionDeneb},
+		{name: "Electra from fork schedule", body: electraBlock, schedule: electraSchedule, expected: spec.DataVersionElectra},
+		{name: "Deneb block at electra slot", body: denebBlock, schedule: electraSchedule},
+		{name: "Version header takes precedence over fork schedule", body: denebBlock, consensusVersion: "deneb", schedule: electraSchedule, expected: spec.DataVersionDeneb},
+		{name: "Invalid body", body: []byte("{}")},
+		{name: "Invalid body with fork schedule", body: []byte("{}"), schedule: electraSchedule, expectedErr: errInvalidSlot},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			block, err := decodeSignedBlindedBeaconBlock(tt.body, false, tt.consensusVersion, tt.schedule)
+			if tt.expected == spec.DataVersionUnknown {
+				require.Error(t, err)
+				if tt.expectedErr != nil {
+					require.ErrorIs(t, err, tt.expectedErr)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, block.Version)
+			slot, err := block.Slot()
+			require.NoError(t, err)
+			require.Equal(t, phase0.Slot(348241), slot)
+		})
+	}
+}
+
+func TestDecodeSignedBlindedBeaconBlockSSZ(t *testing.T) {
+	body, err := os.ReadFile("../testdata/signed-blinded-beacon-block-electra.json")
+	require.NoError(t, err)
+	block, err := decodeSignedBlindedBeaconBlock(body, false, "electra", nil)
+	require.NoError(t, err)
+	sszBody, err := block.Electra.MarshalSSZ()
+	require.NoError(t, err)
+
+	slot, err := signedBlindedBeaconBlockSlot(sszBody, true)
+	require.NoError(t, err)
+	require.Equal(t, uint64(348241), slot)
+
+	for _, consensusVersion := range []string{"electra", ""} {
+		decoded, err := decodeSignedBlindedBeaconBlock(sszBody, true, consensusVersion, nil)
+		require.NoError(t, err)
+		require.Equal(t, spec.DataVersionElectra, decoded.Version)
+		require.Equal(t, block.Electra, decoded.Electra)
+	}
+
+	_, err = decodeSignedBlindedBeaconBlock(sszBody, true, "deneb", nil)
+	require.Error(t, err)
+	_, err = signedBlindedBeaconBlockSlot(sszBody[:2], true)
+	require.ErrorIs(t, err, errInvalidSlot)
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+var errEquivocation = errors.New("a different block was already submitted for this slot and proposer")
+
+// signedBlockRetentionSlots is the number of slots before the latest submitted block for which the submitted blocks
+// are remembered. Relays don't accept blocks for older slots anyway.
+const signedBlockRetentionSlots = 64
+
+type signedBlockKey struct {
+	slot          uint64
+	proposerIndex uint64
+}
+
+// signedBlockRecord is the record of a submitted block, as stored on disk
+type signedBlockRecord struct {
+	Slot          uint64      `json:"slot"`
+	ProposerIndex uint64      `json:"proposer_index"`
+	BlockRoot     phase0.Root `json:"block_root"`
+}
+
+// signedBlockStore records the root of the signed blinded block submitted for each slot and proposer, so that a
+// second, different block is never revealed to the relays. With a path, the records are saved to disk before a
+// block is accepted, and loaded back on startup.
+type signedBlockStore struct {
+	path   string
+	blocks map[signedBlockKey]phase0.Root
+	mu     sync.Mutex
+}
+
+// newSignedBlockStore returns a store saved to path, or kept in memory only if path is empty
+func newSignedBlockStore(path string) (*signedBlockStore, error) {
+	s := &signedBlockStore{
+		path:   path,
+		blocks: make(map[signedBlockKey]phase0.Root),
+	}
+	if path == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read signed blocks file: %w", err)
+	}
+	var records []signedBlockRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode signed blocks file %s: %w", path, err)
+	}
+	for _, record := range records {
+		s.blocks[signedBlockKey{slot: record.Slot, proposerIndex: record.ProposerIndex}] = record.BlockRoot
+	}
+	return s, nil
+}
+
+// record records the block submitted for a slot by a proposer. It returns errEquivocation if a different block was
+// submitted before, and whether the same block was submitted before.
+func (s *signedBlockStore) record(slot, proposerIndex uint64, root phase0.Root) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := signedBlockKey{slot: slot, proposerIndex: proposerIndex}
+	if previous, ok := s.blocks[key]; ok {
+		if previous != root {
+			return false, fmt.Errorf("%w: block root %s submitted before", errEquivocation, previous.String())
+		}
+		return true, nil
+	}
+
+	s.blocks[key] = root
+	s.prune(slot)
+	if err := s.save(); err != nil {
+		// Not on disk, so the block must not be revealed
+		delete(s.blocks, key)
+		return false, err
+	}
+	return false, nil
+}
+
+// prune forgets the blocks of the slots more than signedBlockRetentionSlots before the latest slot
+func (s *signedBlockStore) prune(latestSlot uint64) {
+	if latestSlot < signedBlockRetentionSlots {
+		return
+	}
+	for key := range s.blocks {
+		if key.slot < latestSlot-signedBlockRetentionSlots {
+			delete(s.blocks, key)
+		}
+	}
+}
+
+// save writes the records to disk
+func (s *signedBlockStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	records := make([]signedBlockRecord, 0, len(s.blocks))
+	for key, root := range s.blocks {
+		records = append(records, signedBlockRecord{Slot: key.slot, ProposerIndex: key.proposerIndex, BlockRoot: root})
+	}
+	slices.SortFunc(records, func(a, b signedBlockRecord) int {
+		return cmp.Or(cmp.Compare(a.Slot, b.Slot), cmp.Compare(a.ProposerIndex, b.ProposerIndex))
+	})
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to save signed blocks: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedBlockStore(t *testing.T) {
+	store, err := newSignedBlockStore("")
+	require.NoError(t, err)
+
+	duplicate, err := store.record(100, 1, phase0.Root{0x01})
+	require.NoError(t, err)
+	require.False(t, duplicate)
+
+	// Same block again
+	duplicate, err = store.record(100, 1, phase0.Root{0x01})
+	require.NoError(t, err)
+	require.True(t, duplicate)
+
+	// Different block for the same slot and proposer
+	_, err = store.record(100, 1, phase0.Root{0x02})
+	require.ErrorIs(t, err, errEquivocation)
+
+	// Other proposer or other slot
+	duplicate, err = store.record(100, 2, phase0.Root{0x02})
+	require.NoError(t, err)
+	require.False(t, duplicate)
+	duplicate, err = store.record(101, 1, phase0.Root{0x02})
+	require.NoError(t, err)
+	require.False(t, duplicate)
+
+	// Old slots are forgotten
+	_, err = store.record(100+signedBlockRetentionSlots+1, 1, phase0.Root{0x03})
+	require.NoError(t, err)
+	require.Len(t, store.blocks, 2)
+}
+
+func TestSignedBlockStoreFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signed-blocks.json")
+	store, err := newSignedBlockStore(path)
+	require.NoError(t, err)
+	_, err = store.record(100, 1, phase0.Root{0x01})
+	require.NoError(t, err)
+	_, err = store.record(101, 2, phase0.Root{0x02})
+	require.NoError(t, err)
+
+	// The records survive a restart
+	store, err = newSignedBlockStore(path)
+	require.NoError(t, err)
+	duplicate, err := store.record(100, 1, phase0.Root{0x01})
+	require.NoError(t, err)
+	require.True(t, duplicate)
+	_, err = store.record(101, 2, phase0.Root{0x03})
+	require.ErrorIs(t, err, errEquivocation)
+
+	// A block which can't be saved is not recorded
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.Mkdir(path, 0o755))
+	_, err = store.record(102, 1, phase0.Root{0x01})
+	require.Error(t, err)
+	require.NotContains(t, store.blocks, signedBlockKey{slot: 102, proposerIndex: 1})
+
+	// Corrupt file
+	path = filepath.Join(t.TempDir(), "signed-blocks.json")
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = newSignedBlockStore(path)
+	require.Error(t, err)
+}
+
+func TestGetPayloadEquivocation(t *testing.T) {
+	path := "/eth/v1/builder/blinded_blocks"
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionDeneb,
+		Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBlock),
+	}
+	rr := backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	// The same block is submitted again
+	rr = backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 2, backend.relays[0].GetRequestCount(path))
+
+	// A different block for the same slot never reaches the relays
+	signedBlindedBlock.Message.StateRoot = phase0.Root{0x01}
+	rr = backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+	require.Contains(t, rr.Body.String(), errEquivocation.Error())
+	require.Equal(t, 2, backend.relays[0].GetRequestCount(path))
+}
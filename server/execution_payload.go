@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	eth2UtilBellatrix "github.com/attestantio/go-eth2-client/util/bellatrix"
+	eth2UtilCapella "github.com/attestantio/go-eth2-client/util/capella"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	errPayloadTransactionsRoot = errors.New("payload transactions root does not match the signed header")
+	errPayloadWithdrawalsRoot  = errors.New("payload withdrawals root does not match the signed header")
+	errPayloadBlockHash        = errors.New("payload does not hash to the block hash")
+)
+
+// Types of the execution layer requests, see EIP-7685
+const (
+	depositRequestType       = 0x00
+	withdrawalRequestType    = 0x01
+	consolidationRequestType = 0x02
+)
+
+// checkExecutionPayload verifies that the execution payload returned by a relay is the one committed to by the
+// signed blinded block: its transactions and withdrawals roots must be the ones of the signed header, and the
+// execution block header rebuilt from the payload must hash to the block hash. requests are the execution requests
+// of the block from Electra on, nil before.
+func checkExecutionPayload(payload *deneb.ExecutionPayload, header *deneb.ExecutionPayloadHeader, parentBeaconRoot phase0.Root, requests *electra.ExecutionRequests) error {
+	txRoot, err := (&eth2UtilBellatrix.ExecutionPayloadTransactions{Transactions: payload.Transactions}).HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("failed to compute transactions root: %w", err)
+	}
+	if txRoot != header.TransactionsRoot {
+		return fmt.Errorf("%w: expected %s, got %s", errPayloadTransactionsRoot, header.TransactionsRoot.String(), phase0.Root(txRoot).String())
+	}
+	withdrawalsRoot, err := (&eth2UtilCapella.ExecutionPayloadWithdrawals{Withdrawals: payload.Withdrawals}).HashTreeRoot()
+	if err != nil {
+		return fmt.Errorf("failed to compute withdrawals root: %w", err)
+	}
+	if withdrawalsRoot != header.WithdrawalsRoot {
+		return fmt.Errorf("%w: expected %s, got %s", errPayloadWithdrawalsRoot, header.WithdrawalsRoot.String(), phase0.Root(withdrawalsRoot).String())
+	}
+
+	blockHash, err := computeBlockHash(payload, parentBeaconRoot, requests)
+	if err != nil {
+		return fmt.Errorf("failed to compute block hash: %w", err)
+	}
+	if blockHash != header.BlockHash || blockHash != payload.BlockHash {
+		return fmt.Errorf("%w: expected %s, got %s", errPayloadBlockHash, header.BlockHash.String(), blockHash.String())
+	}
+	return nil
+}
+
+// electraBlockHeader is the execution block header from Prague on, with the requests hash of EIP-7685 the
+// go-ethereum header type doesn't have yet. The fields are RLP encoded in this order.
+type electraBlockHeader struct {
+	ParentHash       common.Hash
+	UncleHash        common.Hash
+	Coinbase         common.Address
+	Root             common.Hash
+	TxHash           common.Hash
+	ReceiptHash      common.Hash
+	Bloom            gethtypes.Bloom
+	Difficulty       *big.Int
+	Number           *big.Int
+	GasLimit         uint64
+	GasUsed          uint64
+	Time             uint64
+	Extra            []byte
+	MixDigest        common.Hash
+	Nonce            gethtypes.BlockNonce
+	BaseFee          *big.Int
+	WithdrawalsHash  common.Hash
+	BlobGasUsed      uint64
+	ExcessBlobGas    uint64
+	ParentBeaconRoot common.Hash
+	RequestsHash     common.Hash
+}
+
+// computeBlockHash returns the hash of the execution block header of a payload. requests are the execution
+// requests from Electra on, nil before.
+func computeBlockHash(payload *deneb.ExecutionPayload, parentBeaconRoot phase0.Root, requests *electra.ExecutionRequests) (phase0.Hash32, error) {
+	header := &gethtypes.Header{
+		ParentHash:       common.Hash(payload.ParentHash),
+		UncleHash:        gethtypes.EmptyUncleHash,
+		Coinbase:         common.Address(payload.FeeRecipient),
+		Root:             common.Hash(payload.StateRoot),
+		TxHash:           gethtypes.DeriveSha(rawTransactions(payload.Transactions), trie.NewStackTrie(nil)),
+		ReceiptHash:      common.Hash(payload.ReceiptsRoot),
+		Bloom:            gethtypes.Bloom(payload.LogsBloom),
+		Difficulty:       common.Big0,
+		Number:           new(big.Int).SetUint64(payload.BlockNumber),
+		GasLimit:         payload.GasLimit,
+		GasUsed:          payload.GasUsed,
+		Time:             payload.Timestamp,
+		Extra:            payload.ExtraData,
+		MixDigest:        common.Hash(payload.PrevRandao),
+		BaseFee:          payload.BaseFeePerGas.ToBig(),
+		WithdrawalsHash:  withdrawalsHash(payload.Withdrawals),
+		BlobGasUsed:      &payload.BlobGasUsed,
+		ExcessBlobGas:    &payload.ExcessBlobGas,
+		ParentBeaconRoot: (*common.Hash)(&parentBeaconRoot),
+	}
+	if requests == nil {
+		return phase0.Hash32(header.Hash()), nil
+	}
+
+	requestsHash, err := executionRequestsHash(requests)
+	if err != nil {
+		return phase0.Hash32{}, err
+	}
+	encoded, err := rlp.EncodeToBytes(&electraBlockHeader{
+		ParentHash:       header.ParentHash,
+		UncleHash:        header.UncleHash,
+		Coinbase:         header.Coinbase,
+		Root:             header.Root,
+		TxHash:           header.TxHash,
+		ReceiptHash:      header.ReceiptHash,
+		Bloom:            header.Bloom,
+		Difficulty:       header.Difficulty,
+		Number:           header.Number,
+		GasLimit:         header.GasLimit,
+		GasUsed:          header.GasUsed,
+		Time:             header.Time,
+		Extra:            header.Extra,
+		MixDigest:        header.MixDigest,
+		BaseFee:          header.BaseFee,
+		WithdrawalsHash:  *header.WithdrawalsHash,
+		BlobGasUsed:      payload.BlobGasUsed,
+		ExcessBlobGas:    payload.ExcessBlobGas,
+		ParentBeaconRoot: common.Hash(parentBeaconRoot),
+		RequestsHash:     requestsHash,
+	})
+	if err != nil {
+		return phase0.Hash32{}, err
+	}
+	return phase0.Hash32(crypto.Keccak256Hash(encoded)), nil
+}
+
+// rawTransactions lists encoded transactions for the transactions trie, without decoding them so that transaction
+// types unknown to go-ethereum hash too
+type rawTransactions []bellatrix.Transaction
+
+func (txs rawTransactions) Len() int { return len(txs) }
+
+func (txs rawTransactions) EncodeIndex(i int, w *bytes.Buffer) {
+	w.Write(txs[i])
+}
+
+// withdrawalsHash returns the root of the withdrawals trie of the execution block header
+func withdrawalsHash(withdrawals []*capella.Withdrawal) *common.Hash {
+	list := make(gethtypes.Withdrawals, len(withdrawals))
+	for i, w := range withdrawals {
+		list[i] = &gethtypes.Withdrawal{
+			Index:     uint64(w.Index),
+			Validator: uint64(w.ValidatorIndex),
+			Address:   common.Address(w.Address),
+			Amount:    uint64(w.Amount),
+		}
+	}
+	hash := gethtypes.DeriveSha(list, trie.NewStackTrie(nil))
+	return &hash
+}
+
+// executionRequestsHash returns the requests hash of the execution block header, the sha256 of the sha256 of each
+// non-empty list of requests prefixed with its type, see EIP-7685
+func executionRequestsHash(requests *electra.ExecutionRequests) (common.Hash, error) {
+	deposits, err := marshalRequests(requests.Deposits)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	withdrawals, err := marshalRequests(requests.Withdrawals)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	consolidations, err := marshalRequests(requests.Consolidations)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	hasher := sha256.New()
+	for _, typedRequests := range [][]byte{
+		append([]byte{depositRequestType}, deposits...),
+		append([]byte{withdrawalRequestType}, withdrawals...),
+		append([]byte{consolidationRequestType}, consolidations...),
+	} {
+		if len(typedRequests) == 1 {
+			continue
+		}
+		hash := sha256.Sum256(typedRequests)
+		hasher.Write(hash[:])
+	}
+	return common.Hash(hasher.Sum(nil)), nil
+}
+
+// marshalRequests concatenates the SSZ encoding of execution requests, which is their encoding in EIP-7685
+func marshalRequests[T interface{ MarshalSSZ() ([]byte, error) }](requests []T) ([]byte, error) {
+	var data []byte
+	for _, request := range requests {
+		encoded, err := request.MarshalSSZ()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, encoded...)
+	}
+	return data, nil
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"crypto/sha256"
+	"math/big"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	builderApi "github.com/attestantio/go-builder-client/api"
+	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/attestantio/go-eth2-client/spec/capella"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+	"github.com/ethereum/go-ethereum/common"
+	gethtypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	boostUtils "github.com/flashbots/go-boost-utils/utils"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+// testExecutionPayload returns a payload with a signed transaction and a withdrawal
+func testExecutionPayload(t *testing.T) *deneb.ExecutionPayload {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := gethtypes.NewCancunSigner(big.NewInt(1))
+	tx, err := gethtypes.SignNewTx(key, signer, &gethtypes.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+		Gas:       21000,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(1),
+	})
+	require.NoError(t, err)
+	encodedTx, err := tx.MarshalBinary()
+	require.NoError(t, err)
+
+	return &deneb.ExecutionPayload{
+		ParentHash:    phase0.Hash32{0x01},
+		FeeRecipient:  bellatrix.ExecutionAddress{0x02},
+		StateRoot:     phase0.Root{0x03},
+		ReceiptsRoot:  phase0.Root{0x04},
+		PrevRandao:    [32]byte{0x05},
+		BlockNumber:   12345,
+		GasLimit:      30_000_000,
+		GasUsed:       21000,
+		Timestamp:     1700000000,
+		ExtraData:     []byte("builder"),
+		BaseFeePerGas: uint256.NewInt(7),
+		Transactions:  []bellatrix.Transaction{encodedTx},
+		Withdrawals: []*capella.Withdrawal{
+			{Index: 1, ValidatorIndex: 2, Address: bellatrix.ExecutionAddress{0x06}, Amount: 3},
+		},
+		BlobGasUsed:   131072,
+		ExcessBlobGas: 262144,
+	}
+}
+
+func TestComputeBlockHash(t *testing.T) {
+	payload := testExecutionPayload(t)
+	parentBeaconRoot := phase0.Root{0x07}
+
+	t.Run("Deneb", func(t *testing.T) {
+		expected, err := boostUtils.ComputeBlockHash(&builderApi.VersionedExecutionPayload{Version: spec.DataVersionDeneb, Deneb: payload}, &parentBeaconRoot)
+		require.NoError(t, err)
+		blockHash, err := computeBlockHash(payload, parentBeaconRoot, nil)
+		require.NoError(t, err)
+		require.Equal(t, expected, blockHash)
+	})
+
+	t.Run("Electra header is the deneb header with the requests hash", func(t *testing.T) {
+		denebBlockHash, err := computeBlockHash(payload, parentBeaconRoot, nil)
+		require.NoError(t, err)
+		blockHash, err := computeBlockHash(payload, parentBeaconRoot, new(electra.ExecutionRequests))
+		require.NoError(t, err)
+		require.NotEqual(t, denebBlockHash, blockHash)
+
+		header := &electraBlockHeader{Difficulty: common.Big0, Number: common.Big0, BaseFee: common.Big1, RequestsHash: common.Hash{0x08}}
+		encoded, err := rlp.EncodeToBytes(header)
+		require.NoError(t, err)
+		var fields []rlp.RawValue
+		require.NoError(t, rlp.DecodeBytes(encoded, &fields))
+
+		denebHeader := &gethtypes.Header{Difficulty: common.Big0, Number: common.Big0, BaseFee: common.Big1, WithdrawalsHash: new(common.Hash), BlobGasUsed: new(uint64), ExcessBlobGas: new(uint64), ParentBeaconRoot: new(common.Hash)}
+		encoded, err = rlp.EncodeToBytes(denebHeader)
+		require.NoError(t, err)
+		var denebFields []rlp.RawValue
+		require.NoError(t, rlp.DecodeBytes(encoded, &denebFields))
+
+		require.Len(t, fields, len(denebFields)+1)
+		require.Equal(t, denebFields, fields[:len(denebFields)])
+	})
+}
+
+func TestExecutionRequestsHash(t *testing.T) {
+	// No requests: the sha256 of nothing
+	hash, err := executionRequestsHash(new(electra.ExecutionRequests))
+	require.NoError(t, err)
+	require.Equal(t, common.HexToHash("0xe3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"), hash)
+
+	// Empty lists are skipped, the hash only depends on the non-empty ones
+	requests := &electra.ExecutionRequests{
+		Withdrawals: []*electra.WithdrawalRequest{{SourceAddress: bellatrix.ExecutionAddress{0x01}, Amount: 1}},
+	}
+	hash, err = executionRequestsHash(requests)
+	require.NoError(t, err)
+	encoded, err := requests.Withdrawals[0].MarshalSSZ()
+	require.NoError(t, err)
+	withdrawalsHash := sha256.Sum256(append([]byte{withdrawalRequestType}, encoded...))
+	require.Equal(t, common.Hash(sha256.Sum256(withdrawalsHash[:])), hash)
+}
+
+func TestCheckExecutionPayload(t *testing.T) {
+	parentBeaconRoot := phase0.Root{0x07}
+	newPayload := func(t *testing.T) (*deneb.ExecutionPayload, *deneb.ExecutionPayloadHeader) {
+		t.Helper()
+		payload := testExecutionPayload(t)
+		header := new(deneb.ExecutionPayloadHeader)
+		sealPayload(t, payload, header, parentBeaconRoot, nil)
+		return payload, header
+	}
+
+	payload, header := newPayload(t)
+	require.NoError(t, checkExecutionPayload(payload, header, parentBeaconRoot, nil))
+
+	payload, header = newPayload(t)
+	payload.Transactions = payload.Transactions[:0]
+	require.ErrorIs(t, checkExecutionPayload(payload, header, parentBeaconRoot, nil), errPayloadTransactionsRoot)
+
+	payload, header = newPayload(t)
+	payload.Withdrawals[0].Amount++
+	require.ErrorIs(t, checkExecutionPayload(payload, header, parentBeaconRoot, nil), errPayloadWithdrawalsRoot)
+
+	payload, header = newPayload(t)
+	payload.StateRoot = phase0.Root{0x09}
+	require.ErrorIs(t, checkExecutionPayload(payload, header, parentBeaconRoot, nil), errPayloadBlockHash)
+
+	payload, header = newPayload(t)
+	require.ErrorIs(t, checkExecutionPayload(payload, header, phase0.Root{0x09}, nil), errPayloadBlockHash)
+
+	payload, header = newPayload(t)
+	require.ErrorIs(t, checkExecutionPayload(payload, header, parentBeaconRoot, new(electra.ExecutionRequests)), errPayloadBlockHash)
+}
+
+func TestGetPayloadInvalidExecutionPayload(t *testing.T) {
+	path := "/eth/v1/builder/blinded_blocks"
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
+
+	// The first relay returns a payload with a state root the block hash doesn't commit to
+	backend := newTestBackend(t, 2, time.Second)
+	invalid := blindedBlockContentsToPayloadDeneb(signedBlindedBlock)
+	invalid.ExecutionPayload.StateRoot = phase0.Root{0x01}
+	backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{Version: spec.DataVersionDeneb, Deneb: invalid}
+	backend.relays[1].ResponseDelay = 100 * time.Millisecond
+	backend.relays[1].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionDeneb,
+		Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBlock),
+	}
+
+	rr := backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	require.Equal(t, 1, backend.relays[1].GetRequestCount(path))
+	require.NotContains(t, rr.Body.String(), phase0.Root{0x01}.String())
+
+	// Without a valid payload, no payload is returned
+	backend.relays[1].GetPayloadResponse = backend.relays[0].GetPayloadResponse
+	rr = backend.request(t, http.MethodPost, path, signedBlindedBlock)
+	require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "mev_boost"
+
+	// Builder API calls, used as the "method" label
+	methodStatus            = "status"
+	methodRegisterValidator = "registerValidator"
+	methodGetHeader         = "getHeader"
+	methodGetPayload        = "getPayload"
+
+	// Error classes, used as the "class" label
+	errorClassTimeout    = "timeout"
+	errorClassHTTPStatus = "http_status"
+	errorClassDecode     = "decode"
+	errorClassRequest    = "request"
+	errorClassSignature  = "signature"
+	errorClassParentHash = "parent_hash"
+	errorClassInvalid    = "invalid_response"
+)
+
+var (
+	relayRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_requests_total",
+		Help:      "Number of requests sent to a relay, by builder API call",
+	}, []string{"relay", "method"})
+
+	relayErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_errors_total",
+		Help:      "Number of failed or rejected relay responses, by builder API call and error class",
+	}, []string{"relay", "method", "class"})
+
+	relayRequestsCancelledTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_requests_cancelled_total",
+		Help:      "Number of requests to a relay cancelled because the beacon node went away, by builder API call",
+	}, []string{"relay", "method"})
+
+	relayRequestsCutOffTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_requests_cut_off_total",
+		Help:      "Number of requests to a relay cut off by a mev-boost deadline, eg. the timing games delay, by builder API call",
+	}, []string{"relay", "method"})
+
+	relayRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "relay_request_duration_seconds",
+		Help:      "Latency of requests to a relay, by builder API call",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.2, 0.3, 0.5, 0.75, 1, 1.5, 2, 3, 5},
+	}, []string{"relay", "method"})
+
+	bidsReceivedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "bids_received_total",
+		Help:      "Number of valid bids received from a relay",
+	}, []string{"relay"})
+
+	bidsFilteredTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "bids_filtered_total",
+		Help:      "Number of valid bids from a relay rejected by a bid filter, eg. min-bid",
+	}, []string{"relay", "filter"})
+
+	gasLimitChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "gas_limit_checks_total",
+		Help:      "Number of getHeader calls whose bids were checked against the registered gas limit, by whether the parent gas limit was known",
+	}, []string{"parent_known"})
+
+	bidsWonTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "bids_won_total",
+		Help:      "Number of auctions won by a bid delivered by a relay",
+	}, []string{"relay"})
+
+	msIntoSlotHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "ms_into_slot",
+		Help:      "Milliseconds into the slot at which a request from the beacon node started",
+		Buckets:   prometheus.LinearBuckets(0, 500, 25),
+	}, []string{"method"})
+
+	payloadWithheldTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "payload_withheld_total",
+		Help:      "Number of getPayload calls where no payload was delivered, by relay that provided the bid",
+	}, []string{"relay"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		relayRequestsTotal,
+		relayErrorsTotal,
+		relayRequestsCancelledTotal,
+		relayRequestsCutOffTotal,
+		relayRequestDuration,
+		bidsReceivedTotal,
+		bidsFilteredTotal,
+		gasLimitChecksTotal,
+		bidsWonTotal,
+		msIntoSlotHistogram,
+		payloadWithheldTotal,
+	)
+}
+
+// relayLabel returns the value of the "relay" label for a relay
+func relayLabel(relay types.RelayEntry) string {
+	return relay.DisplayName()
+}
+
+// classifyRelayError maps an error returned by SendHTTPRequest to an error class
+func classifyRelayError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return errorClassTimeout
+	case errors.Is(err, errHTTPErrorResponse):
+		return errorClassHTTPStatus
+	case errors.Is(err, errDecodeResponse):
+		return errorClassDecode
+	default:
+		return errorClassRequest
+	}
+}
+
+// recordRelayRequest updates the request, latency and error metrics for a single relay call. Requests cancelled
+// because the beacon node went away, or cut off by a mev-boost deadline, are counted apart from relay errors.
+func recordRelayRequest(relay types.RelayEntry, method string, start time.Time, err error) {
+	label := relayLabel(relay)
+	relayRequestsTotal.WithLabelValues(label, method).Inc()
+	if errors.Is(err, context.Canceled) {
+		relayRequestsCancelledTotal.WithLabelValues(label, method).Inc()
+		return
+	}
+	if errors.Is(err, errDeadlineCutOff) {
+		relayRequestsCutOffTotal.WithLabelValues(label, method).Inc()
+		return
+	}
+	relayRequestDuration.WithLabelValues(label, method).Observe(time.Since(start).Seconds())
+	if err != nil {
+		recordRelayError(relay, method, classifyRelayError(err))
+	}
+}
+
+// recordRelayError counts a failed or rejected relay response
+func recordRelayError(relay types.RelayEntry, method, class string) {
+	relayErrorsTotal.WithLabelValues(relayLabel(relay), method, class).Inc()
+}
+
+// recordMsIntoSlot observes how late into the slot a beacon node request starts. Requests arriving before the slot
+// start are not observed, nor are any requests without a genesis time to know the slot start.
+func (m *BoostService) recordMsIntoSlot(method string, msIntoSlot int64) {
+	if m.genesisTime == 0 || msIntoSlot < 0 {
+		return
+	}
+	msIntoSlotHistogram.WithLabelValues(method).Observe(float64(msIntoSlot))
+}
+
+// StartMetricsServer starts the HTTP server exposing Prometheus metrics, if a metrics address is configured
+func (m *BoostService) StartMetricsServer() error {
+	if m.metricsAddr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", m.metricsAddr)
+	if err != nil {
+		return err
+	}
+	return m.ServeMetrics(listener)
+}
+
+// ServeMetrics serves the Prometheus metrics on the listener until the service is closed
+func (m *BoostService) ServeMetrics(listener net.Listener) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second,
+	}
+
+	m.metricsLock.Lock()
+	if m.metricsSrv != nil {
+		m.metricsLock.Unlock()
+		listener.Close()
+		return errServerAlreadyRunning
+	}
+	m.metricsSrv = srv
+	m.metricsLock.Unlock()
+
+	err := srv.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// closeMetricsServer stops the metrics server, if running
+func (m *BoostService) closeMetricsServer() error {
+	m.metricsLock.Lock()
+	defer m.metricsLock.Unlock()
+	if m.metricsSrv == nil {
+		return nil
+	}
+	return m.metricsSrv.Close()
+}
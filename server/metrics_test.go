@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyRelayError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "Deadline exceeded",
+			err:      fmt.Errorf("request failed: %w", context.DeadlineExceeded),
+			expected: errorClassTimeout,
+		},
+		{
+			name:     "HTTP error response",
+			err:      fmt.Errorf("%w: %d / %s", errHTTPErrorResponse, 500, "internal server error"),
+			expected: errorClassHTTPStatus,
+		},
+		{
+			name:     "Decode error",
+			err:      fmt.Errorf("%w %s: %w", errDecodeResponse, "{", io.ErrUnexpectedEOF),
+			expected: errorClassDecode,
+		},
+		{
+			name:     "Other error",
+			err:      io.EOF,
+			expected: errorClassRequest,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, classifyRelayError(tt.err))
+		})
+	}
+}
+
+func TestRelayMetrics(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	t.Run("getHeader updates request, bid and win counters", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		relay := relayLabel(backend.relays[0].RelayEntry)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		require.InDelta(t, 1, testutil.ToFloat64(relayRequestsTotal.WithLabelValues(relay, methodGetHeader)), 0)
+		require.InDelta(t, 1, testutil.ToFloat64(bidsReceivedTotal.WithLabelValues(relay)), 0)
+		require.InDelta(t, 1, testutil.ToFloat64(bidsWonTotal.WithLabelValues(relay)), 0)
+	})
+
+	t.Run("getHeader counts signature failures", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		relay := relayLabel(backend.relays[0].RelayEntry)
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345,
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+		backend.relays[0].GetHeaderResponse.Deneb.Signature[0] = 0x1
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.InDelta(t, 1, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relay, methodGetHeader, errorClassSignature)), 0)
+		require.InDelta(t, 0, testutil.ToFloat64(bidsReceivedTotal.WithLabelValues(relay)), 0)
+	})
+}
+
+func TestRelayRequestsCancelled(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	backend := newTestBackend(t, 1, time.Second)
+	backend.relays[0].ResponseDelay = 500 * time.Millisecond
+	relay := relayLabel(backend.relays[0].RelayEntry)
+
+	// The beacon node goes away before the relay responds
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	time.AfterFunc(50*time.Millisecond, cancel)
+	req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+
+	start := time.Now()
+	backend.boost.getRouter().ServeHTTP(rr, req)
+	require.Less(t, time.Since(start), 400*time.Millisecond)
+
+	require.InDelta(t, 1, testutil.ToFloat64(relayRequestsCancelledTotal.WithLabelValues(relay, methodGetHeader)), 0)
+	require.InDelta(t, 0, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relay, methodGetHeader, errorClassTimeout)), 0)
+	require.InDelta(t, 0, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relay, methodGetHeader, errorClassRequest)), 0)
+}
+
+func TestMsIntoSlotMetric(t *testing.T) {
+	samples := func() (uint64, float64) {
+		metric := &dto.Metric{}
+		require.NoError(t, msIntoSlotHistogram.WithLabelValues(methodGetHeader).(prometheus.Histogram).Write(metric))
+		return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+	}
+	backend := newTestBackend(t, 1, time.Second)
+	count, sum := samples()
+
+	// Without a genesis time, or before the slot start, requests are not observed
+	backend.boost.recordMsIntoSlot(methodGetHeader, 1500)
+	backend.boost.genesisTime = 1000
+	backend.boost.recordMsIntoSlot(methodGetHeader, -500)
+	newCount, newSum := samples()
+	require.Equal(t, count, newCount)
+	require.InDelta(t, sum, newSum, 0)
+
+	backend.boost.recordMsIntoSlot(methodGetHeader, 1500)
+	newCount, newSum = samples()
+	require.Equal(t, count+1, newCount)
+	require.InDelta(t, sum+1500, newSum, 0)
+}
+
+func TestMetricsServer(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	listener, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	served := make(chan error, 1)
+	go func() {
+		served <- backend.boost.ServeMetrics(listener)
+	}()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/metrics") //nolint:noctx
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.True(t, strings.Contains(string(body), "go_goroutines"))
+
+	// The server stops with the service
+	require.NoError(t, backend.boost.Close())
+	require.NoError(t, <-served)
+}
@@ -6,21 +6,25 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	builderApi "github.com/attestantio/go-builder-client/api"
 	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
+	builderApiElectra "github.com/attestantio/go-builder-client/api/electra"
 	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
 	builderSpec "github.com/attestantio/go-builder-client/spec"
 	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/flashbots/go-boost-utils/bls"
 	"github.com/flashbots/go-boost-utils/ssz"
+	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/params"
 	"github.com/flashbots/mev-boost/server/types"
 	"github.com/gorilla/mux"
@@ -51,8 +55,9 @@ type Relay struct {
 	RelayEntry types.RelayEntry
 
 	// Used to count each Request made to the relay, either if it fails or not, for each method
-	mu           sync.Mutex
-	requestCount map[string]int
+	mu                 sync.Mutex
+	requestCount       map[string]int
+	requestContentType map[string]string
 
 	// Overriders
 	handlerOverrideRegisterValidator func(w http.ResponseWriter, req *http.Request)
@@ -63,6 +68,13 @@ type Relay struct {
 	GetHeaderResponse  *builderSpec.VersionedSignedBuilderBid
 	GetPayloadResponse *builderApi.VersionedSubmitBlindedBlockResponse
 
+	// HeaderTimestamp is the timestamp of the headers made by MakeGetHeaderResponse, by default the start of slot 1
+	// with a genesis time of 0
+	HeaderTimestamp uint64
+
+	// SupportsSSZ makes the relay accept SSZ request bodies and respond with SSZ when accepted by the client
+	SupportsSSZ bool
+
 	// Server section
 	Server        *httptest.Server
 	ResponseDelay time.Duration
@@ -72,7 +84,7 @@ type Relay struct {
 // A secret key must be provided to sign default and custom response messages
 func NewRelay(t *testing.T) *Relay {
 	t.Helper()
-	relay := &Relay{t: t, secretKey: mockRelaySecretKey, publicKey: mockRelayPublicKey, requestCount: make(map[string]int)}
+	relay := &Relay{t: t, secretKey: mockRelaySecretKey, publicKey: mockRelayPublicKey, requestCount: make(map[string]int), requestContentType: make(map[string]string), HeaderTimestamp: config.SlotTimeSec}
 
 	// Initialize server
 	relay.Server = httptest.NewServer(relay.getRouter())
@@ -94,6 +106,7 @@ func (m *Relay) newTestMiddleware(next http.Handler) http.Handler {
 			m.mu.Lock()
 			url := r.URL.EscapedPath()
 			m.requestCount[url]++
+			m.requestContentType[url] = r.Header.Get("Content-Type")
 			m.mu.Unlock()
 
 			// Artificial Delay
@@ -128,6 +141,31 @@ func (m *Relay) GetRequestCount(path string) int {
 	return m.requestCount[path]
 }
 
+// GetRequestContentType returns the Content-Type header of the last Request made to a specific URL
+func (m *Relay) GetRequestContentType(path string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requestContentType[path]
+}
+
+// acceptsSSZ returns whether the relay supports SSZ and the request accepts an SSZ response
+func (m *Relay) acceptsSSZ(req *http.Request) bool {
+	return m.SupportsSSZ && strings.Contains(req.Header.Get("Accept"), "application/octet-stream")
+}
+
+// writeSSZ writes an SSZ encoded response of the given version
+func writeSSZ(w http.ResponseWriter, version spec.DataVersion, marshaler interface{ MarshalSSZ() ([]byte, error) }) {
+	data, err := marshaler.MarshalSSZ()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Eth-Consensus-Version", version.String())
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
+
 // By default, handleRoot returns the relay's status
 func (m *Relay) handleRoot(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -177,7 +215,8 @@ func (m *Relay) MakeGetHeaderResponse(value uint64, blockHash, parentHash, publi
 				BlockHash:       HexToHash(blockHash),
 				ParentHash:      HexToHash(parentHash),
 				WithdrawalsRoot: phase0.Root{},
-				BaseFeePerGas:   uint256.NewInt(0),
+				BaseFeePerGas:   uint256.NewInt(7),
+				Timestamp:       m.HeaderTimestamp,
 			},
 			BlobKZGCommitments: make([]deneb.KZGCommitment, 0),
 			Value:              uint256.NewInt(value),
@@ -195,12 +234,53 @@ func (m *Relay) MakeGetHeaderResponse(value uint64, blockHash, parentHash, publi
 				Signature: signature,
 			},
 		}
+	case spec.DataVersionElectra:
+		message := &builderApiElectra.BuilderBid{
+			Header: &deneb.ExecutionPayloadHeader{
+				BlockHash:       HexToHash(blockHash),
+				ParentHash:      HexToHash(parentHash),
+				WithdrawalsRoot: phase0.Root{},
+				BaseFeePerGas:   uint256.NewInt(7),
+				Timestamp:       m.HeaderTimestamp,
+			},
+			BlobKZGCommitments: make([]deneb.KZGCommitment, 0),
+			ExecutionRequests: &electra.ExecutionRequests{
+				Deposits:       make([]*electra.DepositRequest, 0),
+				Withdrawals:    make([]*electra.WithdrawalRequest, 0),
+				Consolidations: make([]*electra.ConsolidationRequest, 0),
+			},
+			Value:  uint256.NewInt(value),
+			Pubkey: HexToPubkey(publicKey),
+		}
+
+		// Sign the message.
+		signature, err := ssz.SignMessage(message, ssz.DomainBuilder, m.secretKey)
+		require.NoError(m.t, err)
+
+		return &builderSpec.VersionedSignedBuilderBid{
+			Version: spec.DataVersionElectra,
+			Electra: &builderApiElectra.SignedBuilderBid{
+				Message:   message,
+				Signature: signature,
+			},
+		}
 	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
 		return nil
 	}
 	return nil
 }
 
+// SignGetHeaderResponse signs a getHeader response again, after its message was changed
+func (m *Relay) SignGetHeaderResponse(bid *builderSpec.VersionedSignedBuilderBid) {
+	var err error
+	if bid.Version == spec.DataVersionElectra {
+		bid.Electra.Signature, err = ssz.SignMessage(bid.Electra.Message, ssz.DomainBuilder, m.secretKey)
+	} else {
+		bid.Deneb.Signature, err = ssz.SignMessage(bid.Deneb.Message, ssz.DomainBuilder, m.secretKey)
+	}
+	require.NoError(m.t, err)
+}
+
 // handleGetHeader handles incoming requests to server.pathGetHeader
 func (m *Relay) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 	m.mu.Lock()
@@ -210,15 +290,11 @@ func (m *Relay) handleGetHeader(w http.ResponseWriter, req *http.Request) {
 		m.handlerOverrideGetHeader(w, req)
 		return
 	}
-	m.defaultHandleGetHeader(w)
+	m.defaultHandleGetHeader(w, req)
 }
 
 // defaultHandleGetHeader returns the default handler for handleGetHeader
-func (m *Relay) defaultHandleGetHeader(w http.ResponseWriter) {
-	// By default, everything will be ok.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-
+func (m *Relay) defaultHandleGetHeader(w http.ResponseWriter, req *http.Request) {
 	// Build the default response.
 	response := m.MakeGetHeaderResponse(
 		12345,
@@ -231,6 +307,18 @@ func (m *Relay) defaultHandleGetHeader(w http.ResponseWriter) {
 		response = m.GetHeaderResponse
 	}
 
+	if m.acceptsSSZ(req) {
+		if response.Version == spec.DataVersionElectra {
+			writeSSZ(w, response.Version, response.Electra)
+		} else {
+			writeSSZ(w, response.Version, response.Deneb)
+		}
+		return
+	}
+
+	// By default, everything will be ok.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -240,24 +328,29 @@ func (m *Relay) defaultHandleGetHeader(w http.ResponseWriter) {
 // MakeGetPayloadResponse is used to create the default or can be used to create a custom response to the getPayload
 // method
 func (m *Relay) MakeGetPayloadResponse(parentHash, blockHash, feeRecipient string, blockNumber uint64, version spec.DataVersion) *builderApi.VersionedSubmitBlindedBlockResponse {
-	return &builderApi.VersionedSubmitBlindedBlockResponse{
-		Version: version,
-		Deneb: &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
-			ExecutionPayload: &deneb.ExecutionPayload{
-				ParentHash:    HexToHash(parentHash),
-				BlockHash:     HexToHash(blockHash),
-				BlockNumber:   blockNumber,
-				FeeRecipient:  HexToAddress(feeRecipient),
-				BaseFeePerGas: uint256.NewInt(0),
-				Withdrawals:   make([]*capella.Withdrawal, 0),
-			},
-			BlobsBundle: &builderApiDeneb.BlobsBundle{
-				Blobs:       make([]deneb.Blob, 0),
-				Commitments: make([]deneb.KZGCommitment, 0),
-				Proofs:      make([]deneb.KZGProof, 0),
-			},
+	bundle := &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
+		ExecutionPayload: &deneb.ExecutionPayload{
+			ParentHash:    HexToHash(parentHash),
+			BlockHash:     HexToHash(blockHash),
+			BlockNumber:   blockNumber,
+			FeeRecipient:  HexToAddress(feeRecipient),
+			BaseFeePerGas: uint256.NewInt(0),
+			Withdrawals:   make([]*capella.Withdrawal, 0),
+		},
+		BlobsBundle: &builderApiDeneb.BlobsBundle{
+			Blobs:       make([]deneb.Blob, 0),
+			Commitments: make([]deneb.KZGCommitment, 0),
+			Proofs:      make([]deneb.KZGProof, 0),
 		},
 	}
+
+	response := &builderApi.VersionedSubmitBlindedBlockResponse{Version: version}
+	if version == spec.DataVersionElectra {
+		response.Electra = bundle
+	} else {
+		response.Deneb = bundle
+	}
+	return response
 }
 
 // handleGetPayload handles incoming requests to server.pathGetPayload
@@ -269,28 +362,50 @@ func (m *Relay) handleGetPayload(w http.ResponseWriter, req *http.Request) {
 		m.handlerOverrideGetPayload(w, req)
 		return
 	}
-	m.DefaultHandleGetPayload(w)
+
+	if req.Header.Get("Content-Type") == "application/octet-stream" && !m.SupportsSSZ {
+		http.Error(w, "unsupported media type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	version := spec.DataVersionDeneb
+	if req.Header.Get("Eth-Consensus-Version") == spec.DataVersionElectra.String() {
+		version = spec.DataVersionElectra
+	}
+	m.defaultHandleGetPayload(w, version, m.acceptsSSZ(req))
 }
 
 // DefaultHandleGetPayload returns the default handler for handleGetPayload
 func (m *Relay) DefaultHandleGetPayload(w http.ResponseWriter) {
-	// By default, everything will be ok.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	m.defaultHandleGetPayload(w, spec.DataVersionDeneb, false)
+}
 
+func (m *Relay) defaultHandleGetPayload(w http.ResponseWriter, version spec.DataVersion, sszResponse bool) {
 	// Build the default response.
 	response := m.MakeGetPayloadResponse(
 		"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
 		"0x534809bd2b6832edff8d8ce4cb0e50068804fd1ef432c8362ad708a74fdc0e46",
 		"0xdb65fEd33dc262Fe09D9a2Ba8F80b329BA25f941",
 		12345,
-		spec.DataVersionDeneb,
+		version,
 	)
 
 	if m.GetPayloadResponse != nil {
 		response = m.GetPayloadResponse
 	}
 
+	if sszResponse {
+		if response.Version == spec.DataVersionElectra {
+			writeSSZ(w, response.Version, response.Electra)
+		} else {
+			writeSSZ(w, response.Version, response.Deneb)
+		}
+		return
+	}
+
+	// By default, everything will be ok.
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -304,6 +419,13 @@ func (m *Relay) OverrideHandleRegisterValidator(method func(w http.ResponseWrite
 	m.handlerOverrideRegisterValidator = method
 }
 
+func (m *Relay) OverrideHandleGetHeader(method func(w http.ResponseWriter, req *http.Request)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlerOverrideGetHeader = method
+}
+
 func (m *Relay) OverrideHandleGetPayload(method func(w http.ResponseWriter, req *http.Request)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
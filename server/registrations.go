@@ -0,0 +1,160 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	"github.com/sirupsen/logrus"
+)
+
+// gasLimitFilterName identifies the gas limit check in the logs and metrics, like the bid filters
+const gasLimitFilterName = "gas-limit"
+
+// registrationStore keeps the latest validator registration of each validator, keyed by lowercase hex pubkey
+type registrationStore struct {
+	registrations map[string]*builderApiV1.ValidatorRegistration
+	mu            sync.RWMutex
+}
+
+// set stores the registrations, unless a registration with a later timestamp is already stored for the validator
+func (s *registrationStore) set(registrations []builderApiV1.SignedValidatorRegistration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.registrations == nil {
+		s.registrations = make(map[string]*builderApiV1.ValidatorRegistration)
+	}
+	for _, registration := range registrations {
+		if registration.Message == nil {
+			continue
+		}
+		key := strings.ToLower(registration.Message.Pubkey.String())
+		if previous, ok := s.registrations[key]; ok && previous.Timestamp.After(registration.Message.Timestamp) {
+			continue
+		}
+		s.registrations[key] = registration.Message
+	}
+}
+
+// get returns the registration of a validator, if any
+func (s *registrationStore) get(pubkey string) (*builderApiV1.ValidatorRegistration, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	registration, ok := s.registrations[strings.ToLower(pubkey)]
+	return registration, ok
+}
+
+// maxGasLimitDelta returns how far the gas limit of a block may move from the gas limit of its parent, ie. by less
+// than parentGasLimit/1024
+func maxGasLimitDelta(parentGasLimit uint64) uint64 {
+	if parentGasLimit < 1024 {
+		return 0
+	}
+	return parentGasLimit/1024 - 1
+}
+
+// expectedGasLimit returns the gas limit of a block whose parent has parentGasLimit, moving as close to the target
+// gas limit as the protocol allows
+func expectedGasLimit(parentGasLimit, target uint64) uint64 {
+	maxDelta := maxGasLimitDelta(parentGasLimit)
+	if target > parentGasLimit {
+		return min(parentGasLimit+maxDelta, target)
+	}
+	if parentGasLimit-target > maxDelta {
+		return parentGasLimit - maxDelta
+	}
+	return target
+}
+
+// gasLimitDistance returns how far a gas limit is from the target
+func gasLimitDistance(gasLimit, target uint64) uint64 {
+	if gasLimit > target {
+		return gasLimit - target
+	}
+	return target - gasLimit
+}
+
+// knownBid returns the bid info of a block mev-boost received as a bid for a slot before the given slot, if any
+func (m *BoostService) knownBid(blockHash string, beforeSlot uint64) (bidInfo, bool) {
+	bid, ok := m.bids.find(blockHash, beforeSlot)
+	return bid.bidInfo, ok
+}
+
+// checkGasLimits drops the bids whose gas limit is wrong for the gas limit registered by the proposer. If mev-boost
+// received the parent block as a bid, its gas limit is known: bids must then move from the parent within the protocol
+// bounds, towards the registered gas limit by as much as allowed, without overshooting it. The parent is rarely known
+// though, as the previous proposer seldom used the same mev-boost. Without it, the bids are checked against each
+// other, as all bids on the same parent must have the same gas limit.
+func (m *BoostService) checkGasLimits(log *logrus.Entry, req GetHeaderRequest, candidates []BidCandidate) []BidCandidate {
+	registration, ok := m.registrations.get(req.Pubkey)
+	if !ok || len(candidates) == 0 {
+		return candidates
+	}
+	target := registration.GasLimit
+	parent, ok := m.knownBid(req.ParentHash, req.Slot)
+	gasLimitChecksTotal.WithLabelValues(strconv.FormatBool(ok)).Inc()
+	if !ok {
+		return checkGasLimitsAgree(log, target, candidates)
+	}
+	maxDelta := maxGasLimitDelta(parent.gasLimit)
+	expected := expectedGasLimit(parent.gasLimit, target)
+
+	valid := candidates[:0]
+	for _, candidate := range candidates {
+		gasLimit := candidate.bidInfo.gasLimit
+		reason := ""
+		switch {
+		case gasLimitDistance(gasLimit, parent.gasLimit) > maxDelta:
+			reason = "gas limit moves from the parent gas limit by more than the protocol allows"
+		case gasLimit != expected:
+			reason = "gas limit does not move towards the registered gas limit as expected"
+		default:
+			valid = append(valid, candidate)
+			continue
+		}
+		rejectGasLimit(log.WithFields(logrus.Fields{
+			"parentGasLimit":     parent.gasLimit,
+			"registeredGasLimit": target,
+			"expectedGasLimit":   expected,
+		}), candidate, reason)
+	}
+	return valid
+}
+
+// checkGasLimitsAgree drops the bids whose gas limit disagrees with the bid closest to the registered gas limit, when
+// the parent gas limit is unknown. The closest bid is trusted only as far as both bids could be built on the same
+// parent within the protocol bounds: a bid further apart has a gas limit out of bounds, and it is not known which one.
+func checkGasLimitsAgree(log *logrus.Entry, target uint64, candidates []BidCandidate) []BidCandidate {
+	reference := candidates[0].bidInfo.gasLimit
+	for _, candidate := range candidates[1:] {
+		if gasLimitDistance(candidate.bidInfo.gasLimit, target) < gasLimitDistance(reference, target) {
+			reference = candidate.bidInfo.gasLimit
+		}
+	}
+
+	valid := candidates[:0]
+	for _, candidate := range candidates {
+		gasLimit := candidate.bidInfo.gasLimit
+		if gasLimit == reference || gasLimitDistance(gasLimit, reference) > 2*maxGasLimitDelta(max(gasLimit, reference)) {
+			valid = append(valid, candidate)
+			continue
+		}
+		rejectGasLimit(log.WithFields(logrus.Fields{
+			"registeredGasLimit": target,
+			"referenceGasLimit":  reference,
+		}), candidate, "gas limit moves less towards the registered gas limit than other bids on the same parent")
+	}
+	return valid
+}
+
+// rejectGasLimit logs and counts a bid dropped by the gas limit check
+func rejectGasLimit(log *logrus.Entry, candidate BidCandidate, reason string) {
+	log.WithFields(logrus.Fields{
+		"filter":    gasLimitFilterName,
+		"url":       candidate.Relay.String(),
+		"blockHash": candidate.BlockHash.String(),
+		"gasLimit":  candidate.bidInfo.gasLimit,
+	}).Warn("bid rejected by filter, " + reason)
+	bidsFilteredTotal.WithLabelValues(relayLabel(candidate.Relay), gasLimitFilterName).Inc()
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
+	builderSpec "github.com/attestantio/go-builder-client/spec"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/bellatrix"
+	"github.com/flashbots/mev-boost/server/mock"
+	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testRegistration(pubkey string, gasLimit uint64, timestamp time.Time) builderApiV1.SignedValidatorRegistration {
+	return builderApiV1.SignedValidatorRegistration{
+		Message: &builderApiV1.ValidatorRegistration{
+			FeeRecipient: bellatrix.ExecutionAddress{0x01},
+			GasLimit:     gasLimit,
+			Timestamp:    timestamp,
+			Pubkey:       mock.HexToPubkey(pubkey),
+		},
+	}
+}
+
+func TestRegistrationStore(t *testing.T) {
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	now := time.Now().Truncate(time.Second)
+	store := registrationStore{}
+
+	_, ok := store.get(pubkey)
+	require.False(t, ok)
+
+	store.set([]builderApiV1.SignedValidatorRegistration{testRegistration(pubkey, 30_000_000, now)})
+	registration, ok := store.get(pubkey)
+	require.True(t, ok)
+	require.Equal(t, uint64(30_000_000), registration.GasLimit)
+
+	// Older registrations are ignored
+	store.set([]builderApiV1.SignedValidatorRegistration{testRegistration(pubkey, 32_000_000, now.Add(-time.Minute))})
+	registration, _ = store.get(pubkey)
+	require.Equal(t, uint64(30_000_000), registration.GasLimit)
+
+	store.set([]builderApiV1.SignedValidatorRegistration{testRegistration(pubkey, 36_000_000, now.Add(time.Minute))})
+	registration, ok = store.get("0x8A1D7B8DD64E0AAFE7EA7B6C95065C9364CF99D38470C12EE807D55F7DE1529AD29CE2C422E0B65E3D5A05C02CACA249")
+	require.True(t, ok)
+	require.Equal(t, uint64(36_000_000), registration.GasLimit)
+}
+
+func TestExpectedGasLimit(t *testing.T) {
+	testCases := []struct {
+		parent   uint64
+		target   uint64
+		expected uint64
+	}{
+		{parent: 30_000_000, target: 36_000_000, expected: 30_029_295},
+		{parent: 36_000_000, target: 30_000_000, expected: 35_964_845},
+		{parent: 30_000_000, target: 30_000_000, expected: 30_000_000},
+		{parent: 30_000_100, target: 30_000_000, expected: 30_000_000},
+		{parent: 29_999_900, target: 30_000_000, expected: 30_000_000},
+	}
+	for _, tt := range testCases {
+		require.Equal(t, tt.expected, expectedGasLimit(tt.parent, tt.target), "parent %d, target %d", tt.parent, tt.target)
+	}
+}
+
+func TestGetHeaderGasLimit(t *testing.T) {
+	parentHash := "0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	path := getHeaderPath(1, mock.HexToHash(parentHash), mock.HexToPubkey(pubkey))
+
+	// The first relay moves the gas limit up, the second one down, on a parent block at height 0
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		for i, bid := range []struct {
+			value     uint64
+			blockHash string
+			gasLimit  uint64
+		}{
+			{13000, "0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7", 30_029_295},
+			{20000, "0xa28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7", 29_970_705},
+		} {
+			response := backend.relays[i].MakeGetHeaderResponse(bid.value, bid.blockHash, parentHash, pubkey, spec.DataVersionDeneb)
+			response.Deneb.Message.Header.GasLimit = bid.gasLimit
+			response.Deneb.Message.Header.BlockNumber = 1
+			backend.relays[i].SignGetHeaderResponse(response)
+			backend.relays[i].GetHeaderResponse = response
+		}
+		return backend
+	}
+	register := func(t *testing.T, backend *testBackend, gasLimit uint64) {
+		t.Helper()
+		payload := []builderApiV1.SignedValidatorRegistration{testRegistration(pubkey, gasLimit, time.Now())}
+		rr := backend.request(t, http.MethodPost, "/eth/v1/builder/validators", payload)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	}
+	bidValue := func(t *testing.T, rr *httptest.ResponseRecorder) *uint256.Int {
+		t.Helper()
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		value, err := resp.Value()
+		require.NoError(t, err)
+		return value
+	}
+
+	t.Run("Without registration", func(t *testing.T) {
+		backend := newBackend(t)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint256.NewInt(20000), bidValue(t, rr))
+	})
+
+	t.Run("Parent gas limit unknown", func(t *testing.T) {
+		backend := newBackend(t)
+		register(t, backend, 36_000_000)
+		known := testutil.ToFloat64(gasLimitChecksTotal.WithLabelValues("false"))
+
+		// The bid moving away from the registered gas limit disagrees with the other bid on the same parent
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint256.NewInt(13000), bidValue(t, rr))
+		require.InDelta(t, known+1, testutil.ToFloat64(gasLimitChecksTotal.WithLabelValues("false")), 0)
+
+		backend = newBackend(t)
+		register(t, backend, 29_000_000)
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint256.NewInt(20000), bidValue(t, rr))
+		relay := relayLabel(backend.relays[0].RelayEntry)
+		require.InDelta(t, 1, testutil.ToFloat64(bidsFilteredTotal.WithLabelValues(relay, gasLimitFilterName)), 0)
+	})
+
+	t.Run("Parent gas limit unknown, bids too far apart to tell", func(t *testing.T) {
+		backend := newBackend(t)
+		register(t, backend, 36_000_000)
+		response := backend.relays[0].GetHeaderResponse
+		response.Deneb.Message.Header.GasLimit = 31_000_000
+		backend.relays[0].SignGetHeaderResponse(response)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint256.NewInt(20000), bidValue(t, rr))
+	})
+
+	t.Run("Bid moving away from the registered gas limit", func(t *testing.T) {
+		backend := newBackend(t)
+		register(t, backend, 36_000_000)
+		backend.boost.bids.put(bidRespKey{slot: 0, blockHash: parentHash}, bidResp{bidInfo: bidInfo{gasLimit: 30_000_000}})
+		known := testutil.ToFloat64(gasLimitChecksTotal.WithLabelValues("true"))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, uint256.NewInt(13000), bidValue(t, rr))
+		require.InDelta(t, known+1, testutil.ToFloat64(gasLimitChecksTotal.WithLabelValues("true")), 0)
+
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bid overshooting the registered gas limit", func(t *testing.T) {
+		backend := newBackend(t)
+		register(t, backend, 30_010_000)
+		backend.boost.bids.put(bidRespKey{slot: 0, blockHash: parentHash}, bidResp{bidInfo: bidInfo{gasLimit: 30_000_000}})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+
+		backend = newBackend(t)
+		register(t, backend, 29_990_000)
+		backend.boost.bids.put(bidRespKey{slot: 0, blockHash: parentHash}, bidResp{bidInfo: bidInfo{gasLimit: 30_000_000}})
+		rr = backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Bid beyond the protocol bounds", func(t *testing.T) {
+		backend := newBackend(t)
+		register(t, backend, 36_000_000)
+		backend.boost.bids.put(bidRespKey{slot: 0, blockHash: parentHash}, bidResp{bidInfo: bidInfo{gasLimit: 29_990_000}})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+	})
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flashbots/mev-boost/server/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// latencyEWMAWeight is the weight of the latest status check in the relay latency moving average
+const latencyEWMAWeight = 0.2
+
+var relayHealthyGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: metricsNamespace,
+	Name:      "relay_healthy",
+	Help:      "Whether the last status check of a relay succeeded",
+}, []string{"relay"})
+
+func init() {
+	prometheus.MustRegister(relayHealthyGauge)
+}
+
+// RelayHealth is the state of a relay as seen by the status checks
+type RelayHealth struct {
+	LastCheck           time.Time
+	LastSuccess         time.Time
+	ConsecutiveFailures int
+	LatencyEWMA         time.Duration
+}
+
+// Healthy returns whether the relay has been checked and its last status check succeeded
+func (h RelayHealth) Healthy() bool {
+	return !h.LastCheck.IsZero() && h.ConsecutiveFailures == 0
+}
+
+// relayHealthCache holds the health of the relays, keyed by relay URL
+type relayHealthCache struct {
+	health map[string]RelayHealth
+	mu     sync.RWMutex
+}
+
+func (c *relayHealthCache) get(relay types.RelayEntry) (RelayHealth, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	health, ok := c.health[relay.String()]
+	return health, ok
+}
+
+// record updates the health of a relay with the result of a status check
+func (c *relayHealthCache) record(relay types.RelayEntry, success bool, latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.health == nil {
+		c.health = make(map[string]RelayHealth)
+	}
+
+	health := c.health[relay.String()]
+	health.LastCheck = time.Now()
+	if success {
+		health.LastSuccess = health.LastCheck
+		health.ConsecutiveFailures = 0
+		if health.LatencyEWMA == 0 {
+			health.LatencyEWMA = latency
+		} else {
+			health.LatencyEWMA = time.Duration(latencyEWMAWeight*float64(latency) + (1-latencyEWMAWeight)*float64(health.LatencyEWMA))
+		}
+		relayHealthyGauge.WithLabelValues(relayLabel(relay)).Set(1)
+	} else {
+		health.ConsecutiveFailures++
+		relayHealthyGauge.WithLabelValues(relayLabel(relay)).Set(0)
+	}
+	c.health[relay.String()] = health
+}
+
+// RelayHealth returns the health of a relay, and false if it has not been checked yet
+func (m *BoostService) RelayHealth(relay types.RelayEntry) (RelayHealth, bool) {
+	return m.relayHealth.get(relay)
+}
+
+// RunRelayHealthChecker checks the status of the relays every relay check interval, until ctx is done. The first
+// check happens after one interval, the startup check being done with CheckRelays.
+func (m *BoostService) RunRelayHealthChecker(ctx context.Context) {
+	if m.relayCheckInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(m.relayCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.log.WithField("numHealthyRelays", m.CheckRelays(ctx)).Debug("checked relay status")
+		}
+	}
+}
+
+// CheckNewRelays checks the status of the configured relays which have not been checked yet, eg. relays added by a
+// config reload, so that they are not reported unhealthy until the next background check. It does nothing unless
+// relay checks are enabled.
+func (m *BoostService) CheckNewRelays(ctx context.Context) {
+	if !m.relayCheck {
+		return
+	}
+	settings := m.runtimeSettings()
+	var relays []types.RelayEntry
+	for _, relay := range settings.Relays {
+		if _, ok := m.relayHealth.get(relay); !ok {
+			relays = append(relays, relay)
+		}
+	}
+	if len(relays) > 0 {
+		m.checkRelays(ctx, settings, relays)
+	}
+}
+
+// numHealthyRelays returns the number of configured relays whose last status check succeeded, checking the relays
+// which have not been checked yet first. Without the health checker running in the background, ie. without a relay
+// check interval, the relays are all checked live.
+func (m *BoostService) numHealthyRelays(ctx context.Context) int {
+	if m.relayCheckInterval <= 0 {
+		return m.CheckRelays(ctx)
+	}
+	m.CheckNewRelays(ctx)
+
+	numHealthy := 0
+	for _, relay := range m.runtimeSettings().Relays {
+		if health, ok := m.relayHealth.get(relay); ok && health.Healthy() {
+			numHealthy++
+		}
+	}
+	return numHealthy
+}
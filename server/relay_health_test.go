@@ -0,0 +1,124 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelayHealthCache(t *testing.T) {
+	backend := newTestBackend(t, 1, time.Second)
+	relay := backend.relays[0].RelayEntry
+	cache := relayHealthCache{}
+
+	_, ok := cache.get(relay)
+	require.False(t, ok)
+
+	cache.record(relay, true, 100*time.Millisecond)
+	health, ok := cache.get(relay)
+	require.True(t, ok)
+	require.True(t, health.Healthy())
+	require.Equal(t, 100*time.Millisecond, health.LatencyEWMA)
+	require.Equal(t, health.LastCheck, health.LastSuccess)
+
+	cache.record(relay, true, 200*time.Millisecond)
+	health, _ = cache.get(relay)
+	require.Equal(t, 120*time.Millisecond, health.LatencyEWMA)
+
+	cache.record(relay, false, 0)
+	cache.record(relay, false, 0)
+	health, _ = cache.get(relay)
+	require.False(t, health.Healthy())
+	require.Equal(t, 2, health.ConsecutiveFailures)
+	require.True(t, health.LastCheck.After(health.LastSuccess))
+	require.Equal(t, 120*time.Millisecond, health.LatencyEWMA)
+
+	cache.record(relay, true, 120*time.Millisecond)
+	health, _ = cache.get(relay)
+	require.True(t, health.Healthy())
+	require.Equal(t, 0, health.ConsecutiveFailures)
+}
+
+func TestStatusFromRelayHealth(t *testing.T) {
+	path := "/eth/v1/builder/status"
+
+	t.Run("Status is answered from the last checks", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayCheckInterval = time.Hour
+		require.Equal(t, 1, backend.boost.CheckRelays(context.Background()))
+
+		for i := 0; i < 3; i++ {
+			rr := backend.request(t, http.MethodGet, path, nil)
+			require.Equal(t, http.StatusOK, rr.Code)
+		}
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+
+		backend.relays[0].Server.Close()
+		require.Equal(t, 0, backend.boost.CheckRelays(context.Background()))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("Status is checked live without a relay check interval", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		require.Equal(t, 1, backend.boost.CheckRelays(context.Background()))
+
+		for i := 0; i < 3; i++ {
+			rr := backend.request(t, http.MethodGet, path, nil)
+			require.Equal(t, http.StatusOK, rr.Code)
+		}
+		require.Equal(t, 4, backend.relays[0].GetRequestCount(path))
+
+		backend.relays[0].Server.Close()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	})
+
+	t.Run("Relays added by a reload are checked", func(t *testing.T) {
+		backend := newTestBackend(t, 2, time.Second)
+		backend.boost.relayCheckInterval = time.Hour
+		settings := backend.boost.runtimeSettings()
+		relays := settings.Relays
+		settings.Relays = relays[:1]
+		require.NoError(t, backend.boost.UpdateSettings(settings))
+		backend.relays[0].Server.Close()
+		require.Equal(t, 0, backend.boost.CheckRelays(context.Background()))
+
+		// The new relay is checked by the next status request, instead of being reported unhealthy
+		settings.Relays = relays
+		require.NoError(t, backend.boost.UpdateSettings(settings))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(path))
+
+		// Relays already checked are left to the health checker
+		backend.boost.CheckNewRelays(context.Background())
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(path))
+	})
+
+	t.Run("Health checker probes relays in the background", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relayCheckInterval = 20 * time.Millisecond
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			backend.boost.RunRelayHealthChecker(ctx)
+			close(done)
+		}()
+		require.Eventually(t, func() bool {
+			return backend.relays[0].GetRequestCount(path) >= 2
+		}, time.Second, 10*time.Millisecond)
+
+		health, ok := backend.boost.RelayHealth(backend.relays[0].RelayEntry)
+		require.True(t, ok)
+		require.True(t, health.Healthy())
+		require.Positive(t, health.LatencyEWMA)
+
+		cancel()
+		<-done
+	})
+}
@@ -1,7 +1,6 @@
 package server
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,7 +18,9 @@ import (
 	builderApi "github.com/attestantio/go-builder-client/api"
 	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
 	builderSpec "github.com/attestantio/go-builder-client/spec"
-	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	eth2Api "github.com/attestantio/go-eth2-client/api"
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/electra"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/ssz"
 	"github.com/flashbots/go-utils/httplogger"
@@ -32,11 +34,21 @@ import (
 
 var (
 	errNoRelays                  = errors.New("no relays")
+	errInvalidPollInterval       = errors.New("timing games poll interval must be positive")
 	errInvalidSlot               = errors.New("invalid slot")
 	errInvalidHash               = errors.New("invalid hash")
 	errInvalidPubkey             = errors.New("invalid pubkey")
 	errNoSuccessfulRelayResponse = errors.New("no successful relay response")
 	errServerAlreadyRunning      = errors.New("server already running")
+	errUnknownPayloadFallback    = errors.New("unknown payload fallback relays policy")
+	errNoBidRelays               = errors.New("no bid known for this block, and no fallback relays")
+)
+
+// Policies for the relays the signed blinded block is sent to when no relay is known to have offered its bid, eg.
+// after a restart, see BoostServiceOpts.PayloadFallbackRelays
+const (
+	PayloadFallbackAll  = "all"
+	PayloadFallbackNone = "none"
 )
 
 var (
@@ -58,38 +70,126 @@ type slotUID struct {
 type BoostServiceOpts struct {
 	Log                   *logrus.Entry
 	ListenAddr            string
+	MetricsAddr           string
 	Relays                []types.RelayEntry
 	RelayMonitors         []*url.URL
 	GenesisForkVersionHex string
 	GenesisTime           uint64
 	RelayCheck            bool
+	RelayCheckInterval    time.Duration
 	RelayMinBid           types.U256Str
 
+	// RelayBreakerThreshold is the number of consecutive getHeader or status failures after which a relay is
+	// skipped for getHeader, for RelayBreakerCooldown or until a status check succeeds. Disabled if 0.
+	RelayBreakerThreshold int
+	RelayBreakerCooldown  time.Duration
+
+	// BidSelector selects the bid returned for getHeader among the valid bids, DefaultBidSelector if nil
+	BidSelector BidSelector
+	// BidFilters are run in order on the valid bids above the min-bid, before the bid selection
+	BidFilters []BidFilter
+
+	// VerifyBlobs verifies the KZG proofs of the blobs returned for getPayload against the block commitments
+	VerifyBlobs bool
+
+	// PayloadFallbackRelays selects the relays the signed blinded block is sent to when no relay is known to have
+	// offered its bid: PayloadFallbackAll (default if empty) or PayloadFallbackNone
+	PayloadFallbackRelays string
+
+	// BidStoreFile keeps the bids returned for getHeader on disk, so that getPayload is sent to the relays which
+	// offered the bid after a restart too, for the last BidStoreRetentionSlots slots
+	// (DefaultBidStoreRetentionSlots if 0). Bids are kept in memory only if empty.
+	BidStoreFile           string
+	BidStoreRetentionSlots uint64
+
+	// SignedBlocksFile saves the record of the blocks submitted for each slot, so that a different block for the
+	// same slot is refused after a restart too. The record is kept in memory only if empty.
+	SignedBlocksFile string
+
+	// ForkSchedule selects the data version of each slot. Without a schedule, the version sent by the beacon node or
+	// found in the request body is used and bids are not checked against the fork of the slot.
+	ForkSchedule *types.ForkSchedule
+
+	RequestTimeoutGetHeader  time.Duration
+	RequestTimeoutGetPayload time.Duration
+	RequestTimeoutRegVal     time.Duration
+	RequestMaxRetries        int
+
+	TimingGames             bool
+	TimingGamesDelay        time.Duration
+	TimingGamesPollInterval time.Duration
+
+	GetHeaderDeadline  time.Duration
+	GetHeaderCutoff    time.Duration
+	GetPayloadDeadline time.Duration
+}
+
+// RuntimeSettings are the settings which can be updated while the service is running
+type RuntimeSettings struct {
+	Relays      []types.RelayEntry
+	RelayMinBid types.U256Str
+
 	RequestTimeoutGetHeader  time.Duration
 	RequestTimeoutGetPayload time.Duration
 	RequestTimeoutRegVal     time.Duration
 	RequestMaxRetries        int
+
+	// TimingGames keeps polling the relays for getHeader every TimingGamesPollInterval, until TimingGamesDelay
+	// into the slot, instead of returning after the first responses
+	TimingGames             bool
+	TimingGamesDelay        time.Duration
+	TimingGamesPollInterval time.Duration
+
+	// Deadlines relative to the slot start, unset if zero. Requests to relays time out at the earlier of the
+	// deadline and the request timeout, and getHeader requests arriving past the cutoff or deadline get no bid.
+	GetHeaderDeadline  time.Duration
+	GetHeaderCutoff    time.Duration
+	GetPayloadDeadline time.Duration
 }
 
 // BoostService - the mev-boost service
 type BoostService struct {
 	listenAddr    string
-	relays        []types.RelayEntry
 	relayMonitors []*url.URL
 	log           *logrus.Entry
 	srv           *http.Server
+	metricsAddr   string
+	metricsSrv    *http.Server
+	metricsLock   sync.Mutex
 	relayCheck    bool
-	relayMinBid   types.U256Str
 	genesisTime   uint64
+	forkSchedule  *types.ForkSchedule
 
 	builderSigningDomain phase0.Domain
-	httpClientGetHeader  http.Client
-	httpClientGetPayload http.Client
-	httpClientRegVal     http.Client
-	requestMaxRetries    int
-
-	bids     map[bidRespKey]bidResp // keeping track of bids, to log the originating relay on withholding
-	bidsLock sync.Mutex
+	relayCheckInterval   time.Duration
+	bidSelector          BidSelector
+	bidFilters           []BidFilter
+	verifyBlobs          bool
+	payloadFallback      string
+
+	// runtime settings, see UpdateSettings
+	relays                   []types.RelayEntry
+	relayMinBid              types.U256Str
+	requestTimeoutGetHeader  time.Duration
+	requestTimeoutGetPayload time.Duration
+	requestTimeoutRegVal     time.Duration
+	requestMaxRetries        int
+	timingGames              bool
+	timingGamesDelay         time.Duration
+	timingGamesPollInterval  time.Duration
+	getHeaderDeadline        time.Duration
+	getHeaderCutoff          time.Duration
+	getPayloadDeadline       time.Duration
+	settingsLock             sync.RWMutex
+
+	bids     *bidCache // keeping track of bids, to log the originating relay on withholding
+	bidStore *bidStore // bids kept across restarts, nil if disabled
+
+	relaySSZ      relaySSZSupport
+	relayHealth   relayHealthCache
+	relayBreakers *relayCircuitBreakers
+	registrations registrationStore
+	signedBlocks  *signedBlockStore
 
 	slotUID     *slotUID
 	slotUIDLock sync.Mutex
@@ -100,40 +200,188 @@ func NewBoostService(opts BoostServiceOpts) (*BoostService, error) {
 	if len(opts.Relays) == 0 {
 		return nil, errNoRelays
 	}
+	if opts.TimingGames && opts.TimingGamesPollInterval <= 0 {
+		return nil, errInvalidPollInterval
+	}
 
 	builderSigningDomain, err := ComputeDomain(ssz.DomainTypeAppBuilder, opts.GenesisForkVersionHex, phase0.Root{}.String())
 	if err != nil {
 		return nil, err
 	}
 
+	payloadFallback := opts.PayloadFallbackRelays
+	switch payloadFallback {
+	case "":
+		payloadFallback = PayloadFallbackAll
+	case PayloadFallbackAll, PayloadFallbackNone:
+	default:
+		return nil, fmt.Errorf("%w: %s", errUnknownPayloadFallback, payloadFallback)
+	}
+
+	signedBlocks, err := newSignedBlockStore(opts.SignedBlocksFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var bidStore *bidStore
+	if opts.BidStoreFile != "" {
+		retentionSlots := opts.BidStoreRetentionSlots
+		if retentionSlots == 0 {
+			retentionSlots = DefaultBidStoreRetentionSlots
+		}
+		bidStore, err = openBidStore(opts.BidStoreFile, retentionSlots)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	bidSelector := opts.BidSelector
+	if bidSelector == nil {
+		bidSelector = DefaultBidSelector{}
+	}
+
 	return &BoostService{
 		listenAddr:    opts.ListenAddr,
+		metricsAddr:   opts.MetricsAddr,
 		relays:        opts.Relays,
 		relayMonitors: opts.RelayMonitors,
 		log:           opts.Log,
 		relayCheck:    opts.RelayCheck,
 		relayMinBid:   opts.RelayMinBid,
 		genesisTime:   opts.GenesisTime,
-		bids:          make(map[bidRespKey]bidResp),
+		forkSchedule:  opts.ForkSchedule,
+		bids:          newBidCache(context.Background(), opts.GenesisTime, time.Duration(config.SlotTimeSec)*time.Second, bidCacheRetentionSlots, bidCacheMaxBids),
+		bidStore:      bidStore,
 		slotUID:       &slotUID{},
-
-		builderSigningDomain: builderSigningDomain,
-		httpClientGetHeader: http.Client{
-			Timeout:       opts.RequestTimeoutGetHeader,
-			CheckRedirect: httpClientDisallowRedirects,
-		},
-		httpClientGetPayload: http.Client{
-			Timeout:       opts.RequestTimeoutGetPayload,
-			CheckRedirect: httpClientDisallowRedirects,
-		},
-		httpClientRegVal: http.Client{
-			Timeout:       opts.RequestTimeoutRegVal,
-			CheckRedirect: httpClientDisallowRedirects,
-		},
-		requestMaxRetries: opts.RequestMaxRetries,
+		relayBreakers: newRelayCircuitBreakers(opts.RelayBreakerThreshold, opts.RelayBreakerCooldown),
+		signedBlocks:  signedBlocks,
+
+		builderSigningDomain:     builderSigningDomain,
+		relayCheckInterval:       opts.RelayCheckInterval,
+		bidSelector:              bidSelector,
+		bidFilters:               opts.BidFilters,
+		verifyBlobs:              opts.VerifyBlobs,
+		payloadFallback:          payloadFallback,
+		requestTimeoutGetHeader:  opts.RequestTimeoutGetHeader,
+		requestTimeoutGetPayload: opts.RequestTimeoutGetPayload,
+		requestTimeoutRegVal:     opts.RequestTimeoutRegVal,
+		requestMaxRetries:        opts.RequestMaxRetries,
+		timingGames:              opts.TimingGames,
+		timingGamesDelay:         opts.TimingGamesDelay,
+		timingGamesPollInterval:  opts.TimingGamesPollInterval,
+		getHeaderDeadline:        opts.GetHeaderDeadline,
+		getHeaderCutoff:          opts.GetHeaderCutoff,
+		getPayloadDeadline:       opts.GetPayloadDeadline,
 	}, nil
 }
 
+// Close stops the metrics server and the background tasks of the service, and closes the bid store
+func (m *BoostService) Close() error {
+	m.bids.Close()
+	err := m.closeMetricsServer()
+	if m.bidStore != nil {
+		err = errors.Join(err, m.bidStore.Close())
+	}
+	return err
+}
+
+// UpdateSettings atomically replaces the runtime settings. Requests already in flight keep using
+// the settings they started with.
+func (m *BoostService) UpdateSettings(settings RuntimeSettings) error {
+	if len(settings.Relays) == 0 {
+		return errNoRelays
+	}
+	if settings.TimingGames && settings.TimingGamesPollInterval <= 0 {
+		return errInvalidPollInterval
+	}
+
+	m.settingsLock.Lock()
+	defer m.settingsLock.Unlock()
+	m.relays = settings.Relays
+	m.relayMinBid = settings.RelayMinBid
+	m.requestTimeoutGetHeader = settings.RequestTimeoutGetHeader
+	m.requestTimeoutGetPayload = settings.RequestTimeoutGetPayload
+	m.requestTimeoutRegVal = settings.RequestTimeoutRegVal
+	m.requestMaxRetries = settings.RequestMaxRetries
+	m.timingGames = settings.TimingGames
+	m.timingGamesDelay = settings.TimingGamesDelay
+	m.timingGamesPollInterval = settings.TimingGamesPollInterval
+	m.getHeaderDeadline = settings.GetHeaderDeadline
+	m.getHeaderCutoff = settings.GetHeaderCutoff
+	m.getPayloadDeadline = settings.GetPayloadDeadline
+	return nil
+}
+
+// runtimeSettings returns a consistent snapshot of the runtime settings
+func (m *BoostService) runtimeSettings() RuntimeSettings {
+	m.settingsLock.RLock()
+	defer m.settingsLock.RUnlock()
+	return RuntimeSettings{
+		Relays:                   m.relays,
+		RelayMinBid:              m.relayMinBid,
+		RequestTimeoutGetHeader:  m.requestTimeoutGetHeader,
+		RequestTimeoutGetPayload: m.requestTimeoutGetPayload,
+		RequestTimeoutRegVal:     m.requestTimeoutRegVal,
+		RequestMaxRetries:        m.requestMaxRetries,
+		TimingGames:              m.timingGames,
+		TimingGamesDelay:         m.timingGamesDelay,
+		TimingGamesPollInterval:  m.timingGamesPollInterval,
+		GetHeaderDeadline:        m.getHeaderDeadline,
+		GetHeaderCutoff:          m.getHeaderCutoff,
+		GetPayloadDeadline:       m.getPayloadDeadline,
+	}
+}
+
+// sendRelayRequest sends a getHeader or getPayload request to a relay with send. SSZ responses are requested unless
+// the relay is known not to support them, and the payload is sent SSZ encoded to relays known to support SSZ. If
+// the relay rejects SSZ with a 406 or 415 response, it is remembered and the request is sent again as JSON.
+func (m *BoostService) sendRelayRequest(relay types.RelayEntry, headers map[string]string, payload any, dst versionedResponse, log *logrus.Entry, send func(headers map[string]string, payload, dst any) (int, error)) (int, error) {
+	supported, known := m.relaySSZ.get(relay)
+	if known && !supported {
+		return send(headers, payload, dst)
+	}
+
+	sszHeaders := make(map[string]string, len(headers)+1)
+	for key, value := range headers {
+		sszHeaders[key] = value
+	}
+	sszHeaders["Accept"] = acceptSSZ
+	sszRequest := payload
+	if marshaler, ok := payload.(sszMarshaler); ok && supported {
+		sszRequest = sszPayload{marshaler}
+	}
+
+	code, err := send(sszHeaders, sszRequest, dst)
+	if code == http.StatusNotAcceptable || code == http.StatusUnsupportedMediaType {
+		log.WithField("code", code).Info("relay does not support SSZ, falling back to JSON")
+		m.relaySSZ.set(relay, false)
+		return send(headers, payload, dst)
+	}
+	if err == nil && dst.isSSZ() && !supported {
+		m.relaySSZ.set(relay, true)
+	}
+	return code, err
+}
+
+// slotTime returns the time at offset into the slot, or the zero time if the offset is not set. Without a genesis
+// time, as on a custom network set up without -genesis-timestamp, the slot start is unknown and the zero time is
+// returned too, so that slot-relative deadlines don't apply.
+func (m *BoostService) slotTime(slot uint64, offset time.Duration) time.Time {
+	if offset <= 0 || m.genesisTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(m.genesisTime+slot*config.SlotTimeSec), 0).Add(offset)
+}
+
+// versionAtSlot returns the data version of the slot according to the fork schedule, if any
+func (m *BoostService) versionAtSlot(slot uint64) (spec.DataVersion, bool) {
+	if m.forkSchedule == nil {
+		return spec.DataVersionUnknown, false
+	}
+	version := m.forkSchedule.VersionAtSlot(slot)
+	return version, version != spec.DataVersionUnknown
+}
+
 func (m *BoostService) respondError(w http.ResponseWriter, code int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
@@ -153,6 +401,28 @@ func (m *BoostService) respondOK(w http.ResponseWriter, response any) {
 	}
 }
 
+// respondVersioned sends the response of a getHeader or getPayload call with its Eth-Consensus-Version header, SSZ
+// encoded if that is the negotiated media type and JSON encoded otherwise
+func (m *BoostService) respondVersioned(w http.ResponseWriter, mediaType string, version spec.DataVersion, response any, marshalSSZ func() ([]byte, error)) {
+	w.Header().Set(HeaderEthConsensusVersion, version.String())
+	if mediaType != MediaTypeOctetStream {
+		m.respondOK(w, response)
+		return
+	}
+
+	data, err := marshalSSZ()
+	if err != nil {
+		m.log.WithError(err).Error("Couldn't encode SSZ response")
+		m.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", MediaTypeOctetStream)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		m.log.WithError(err).Error("Couldn't write SSZ response")
+	}
+}
+
 func (m *BoostService) getRouter() http.Handler {
 	r := mux.NewRouter()
 	r.HandleFunc("/", m.handleRoot)
@@ -173,8 +443,6 @@ func (m *BoostService) StartHTTPServer() error {
 		return errServerAlreadyRunning
 	}
 
-	go m.startBidCacheCleanupTask()
-
 	m.srv = &http.Server{
 		Addr:    m.listenAddr,
 		Handler: m.getRouter(),
@@ -194,26 +462,14 @@ func (m *BoostService) StartHTTPServer() error {
 	return err
 }
 
-func (m *BoostService) startBidCacheCleanupTask() {
-	for {
-		time.Sleep(1 * time.Minute)
-		m.bidsLock.Lock()
-		for k, bidResp := range m.bids {
-			if time.Since(bidResp.t) > 3*time.Minute {
-				delete(m.bids, k)
-			}
-		}
-		m.bidsLock.Unlock()
-	}
-}
-
 func (m *BoostService) sendValidatorRegistrationsToRelayMonitors(payload []builderApiV1.SignedValidatorRegistration) {
 	log := m.log.WithField("method", "sendValidatorRegistrationsToRelayMonitors").WithField("numRegistrations", len(payload))
+	client := relayHTTPClient(m.runtimeSettings().RequestTimeoutRegVal, 0)
 	for _, relayMonitor := range m.relayMonitors {
 		go func(relayMonitor *url.URL) {
 			url := types.GetURI(relayMonitor, params.PathRegisterValidator)
 			log = log.WithField("url", url)
-			_, err := SendHTTPRequest(context.Background(), m.httpClientRegVal, http.MethodPost, url, "", nil, payload, nil)
+			_, err := SendHTTPRequest(context.Background(), client, http.MethodPost, url, "", nil, payload, nil)
 			if err != nil {
 				log.WithError(err).Warn("error calling registerValidator on relay monitor")
 				return
@@ -227,11 +483,11 @@ func (m *BoostService) handleRoot(w http.ResponseWriter, _ *http.Request) {
 	m.respondOK(w, nilResponse)
 }
 
-// handleStatus sends calls to the status endpoint of every relay.
-// It returns OK if at least one returned OK, and returns error otherwise.
-func (m *BoostService) handleStatus(w http.ResponseWriter, _ *http.Request) {
+// handleStatus returns OK if the last status check of at least one relay succeeded, and returns error otherwise.
+// The relays are checked in the background, see RunRelayHealthChecker.
+func (m *BoostService) handleStatus(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set(HeaderKeyVersion, config.Version)
-	if !m.relayCheck || m.CheckRelays() > 0 {
+	if !m.relayCheck || m.numHealthyRelays(req.Context()) > 0 {
 		m.respondOK(w, nilResponse)
 	} else {
 		m.respondError(w, http.StatusServiceUnavailable, "all relays are unavailable")
@@ -255,20 +511,33 @@ func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.
 		"ua":               ua,
 	})
 
+	// Remember the preferences of the validators, to check the bids against them
+	m.registrations.set(payload)
+
 	// Add request headers
 	headers := map[string]string{
 		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
 	}
 
-	relayRespCh := make(chan error, len(m.relays))
+	// Registrations still in flight once a relay accepted them are completed, unless the beacon node went away
+	ctx, done := callerContext(req)
+	defer done()
 
-	for _, relay := range m.relays {
+	settings := m.runtimeSettings()
+	relayRespCh := make(chan error, len(settings.Relays))
+
+	for _, relay := range settings.Relays {
 		go func(relay types.RelayEntry) {
 			url := relay.GetURI(params.PathRegisterValidator)
 			log := log.WithField("url", url)
 
-			_, err := SendHTTPRequest(context.Background(), m.httpClientRegVal, http.MethodPost, url, ua, headers, payload, nil)
-			if err != nil {
+			client := relayHTTPClient(settings.RequestTimeoutRegVal, relay.TimeoutRegVal)
+			start := time.Now()
+			_, err := SendHTTPRequest(ctx, client, http.MethodPost, url, ua, relayRequestHeaders(relay, headers), payload, nil)
+			recordRelayRequest(relay, methodRegisterValidator, start, err)
+			if errors.Is(err, context.Canceled) {
+				log.Info("request cancelled, the beacon node went away")
+			} else if err != nil {
 				log.WithError(err).Warn("error calling registerValidator on relay")
 			}
 			relayRespCh <- err
@@ -277,7 +546,7 @@ func (m *BoostService) handleRegisterValidator(w http.ResponseWriter, req *http.
 
 	go m.sendValidatorRegistrationsToRelayMonitors(payload)
 
-	for i := 0; i < len(m.relays); i++ {
+	for i := 0; i < len(settings.Relays); i++ {
 		respErr := <-relayRespCh
 		if respErr == nil {
 			m.respondOK(w, nilResponse)
@@ -321,6 +590,12 @@ func (m *BoostService) handleGetHeader(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	mediaType, ok := negotiateMediaType(req.Header.Get("Accept"))
+	if !ok {
+		m.respondError(w, http.StatusNotAcceptable, errNotAcceptable.Error())
+		return
+	}
+
 	// Make sure we have a uid for this slot
 	m.slotUIDLock.Lock()
 	if m.slotUID.slot < _slot {
@@ -333,143 +608,223 @@ func (m *BoostService) handleGetHeader(w http.ResponseWriter, req *http.Request)
 
 	// Log how late into the slot the request starts
 	slotStartTimestamp := m.genesisTime + _slot*config.SlotTimeSec
-	msIntoSlot := uint64(time.Now().UTC().UnixMilli()) - slotStartTimestamp*1000
+	msIntoSlot := time.Now().UTC().UnixMilli() - int64(slotStartTimestamp)*1000
 	log.WithFields(logrus.Fields{
 		"genesisTime": m.genesisTime,
 		"slotTimeSec": config.SlotTimeSec,
 		"msIntoSlot":  msIntoSlot,
 	}).Infof("getHeader request start - %d milliseconds into slot %d", msIntoSlot, _slot)
+	m.recordMsIntoSlot(methodGetHeader, msIntoSlot)
+
+	// Refuse requests arriving too late into the slot for a bid to be useful
+	settings := m.runtimeSettings()
+	deadline := m.slotTime(_slot, settings.GetHeaderDeadline)
+	if bnDeadline := beaconNodeDeadline(req, time.Now(), time.Duration(config.BeaconNodeTimeoutMarginMs)*time.Millisecond); !bnDeadline.IsZero() {
+		// Don't keep waiting for bids after the beacon node has given up on the request
+		log = log.WithField("beaconNodeTimeoutMs", req.Header.Get(HeaderTimeoutMs))
+		deadline = earliestDeadline(deadline, bnDeadline)
+	}
+	cutoff := earliestDeadline(m.slotTime(_slot, settings.GetHeaderCutoff), deadline)
+	if !cutoff.IsZero() && !time.Now().Before(cutoff) {
+		log.WithField("cutoffMs", cutoff.Sub(time.Unix(int64(slotStartTimestamp), 0)).Milliseconds()).Warn("getHeader request past the cutoff, not requesting bids")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
 	// Add request headers
+	requestTimeMs := fmt.Sprintf("%d", time.Now().UTC().UnixMilli())
 	headers := map[string]string{
-		HeaderKeySlotUID:      slotUID.String(),
-		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
+		HeaderKeySlotUID:       slotUID.String(),
+		HeaderStartTimeUnixMS:  requestTimeMs,
+		HeaderDateMilliseconds: requestTimeMs,
+	}
+	if version, ok := m.versionAtSlot(_slot); ok {
+		headers[HeaderEthConsensusVersion] = version.String()
 	}
 	// Prepare relay responses
-	result := bidResp{}                                 // the final response, containing the highest bid (if any)
+	var candidates []BidCandidate                       // the valid bids received, to select the response from
 	relays := make(map[BlockHashHex][]types.RelayEntry) // relays that sent the bid for a specific blockHash
+	// Filters applied to the bids
+	filterRequest := GetHeaderRequest{Slot: _slot, ParentHash: parentHashHex, Pubkey: pubkey}
+	validityFilters := m.validityBidFilters(config.SkipRelaySignatureCheck)
+	policyFilters := append([]BidFilter{minBidFilter{minBid: settings.RelayMinBid}}, m.bidFilters...)
 	// Call the relays
 	var mu sync.Mutex
 	var wg sync.WaitGroup
-	for _, relay := range m.relays {
-		wg.Add(1)
-		go func(relay types.RelayEntry) {
-			defer wg.Done()
-			path := fmt.Sprintf("/eth/v1/builder/header/%s/%s/%s", slot, parentHashHex, pubkey)
-			url := relay.GetURI(path)
-			log := log.WithField("url", url)
-			responsePayload := new(builderSpec.VersionedSignedBuilderBid)
-			code, err := SendHTTPRequest(context.Background(), m.httpClientGetHeader, http.MethodGet, url, ua, headers, nil, responsePayload)
-			if err != nil {
-				log.WithError(err).Warn("error making request to relay")
-				return
-			}
 
-			if code == http.StatusNoContent {
-				log.Debug("no-content response")
-				return
-			}
-
-			// Skip if payload is empty
-			if responsePayload.IsEmpty() {
-				return
-			}
-
-			// Getting the bid info will check if there are missing fields in the response
-			bidInfo, err := parseBidInfo(responsePayload)
-			if err != nil {
-				log.WithError(err).Warn("error parsing bid info")
-				return
-			}
-
-			if bidInfo.blockHash == nilHash {
-				log.Warn("relay responded with empty block hash")
-				return
+	// With timing games, the relays are polled repeatedly until the deadline into the slot, as bid values rise
+	// over the slot, and the best bid seen by then is returned
+	var pollDeadline time.Time
+	if settings.TimingGames {
+		pollDeadline = earliestDeadline(m.slotTime(_slot, settings.TimingGamesDelay), deadline)
+	}
+	for poll := 1; ; poll++ {
+		pollStart := time.Now()
+		log.WithField("poll", poll).Debug("requesting bids from relays")
+		for priority, relay := range settings.Relays {
+			// Sideline relays failing repeatedly, they are probed again by the status checks or after the cooldown
+			if !m.relayBreakers.allow(relay) {
+				log.WithField("url", relay.String()).Debug("relay circuit breaker open, skipping relay")
+				continue
 			}
+			wg.Add(1)
+			go func(priority int, relay types.RelayEntry) {
+				defer wg.Done()
+				path := fmt.Sprintf("/eth/v1/builder/header/%s/%s/%s", slot, parentHashHex, pubkey)
+				url := relay.GetURI(path)
+				log := log.WithField("url", url)
+				responsePayload := new(builderSpec.VersionedSignedBuilderBid)
+				client := relayHTTPClient(settings.RequestTimeoutGetHeader, relay.TimeoutGetHeader)
+				capDeadline := earliestDeadline(deadline, pollDeadline)
+				capTimeout(&client, capDeadline)
+				start := time.Now()
+				code, err := m.sendRelayRequest(relay, relayRequestHeaders(relay, headers), nil, &builderBidResponse{VersionedSignedBuilderBid: responsePayload}, log,
+					func(headers map[string]string, payload, dst any) (int, error) {
+						return SendHTTPRequest(req.Context(), client, http.MethodGet, url, ua, headers, payload, dst)
+					})
+				// A timeout past the capped deadline is not the relay's fault, and must not trip its circuit breaker
+				err = cutOffByDeadline(err, capDeadline)
+				recordRelayRequest(relay, methodGetHeader, start, err)
+				if errors.Is(err, context.Canceled) {
+					log.Info("request cancelled, the beacon node went away")
+					return
+				} else if errors.Is(err, errDeadlineCutOff) {
+					log.Debug("request cut off by the deadline")
+					return
+				} else if err != nil {
+					log.WithError(err).Warn("error making request to relay")
+					m.relayBreakers.record(relay, false)
+					return
+				}
+				m.relayBreakers.record(relay, true)
 
-			valueEth := weiBigIntToEthBigFloat(bidInfo.value.ToBig())
-			log = log.WithFields(logrus.Fields{
-				"blockNumber": bidInfo.blockNumber,
-				"blockHash":   bidInfo.blockHash.String(),
-				"txRoot":      bidInfo.txRoot.String(),
-				"value":       valueEth.Text('f', 18),
-			})
+				if code == http.StatusNoContent {
+					log.Debug("no-content response")
+					return
+				}
 
-			if relay.PublicKey.String() != bidInfo.pubkey.String() {
-				log.Errorf("bid pubkey mismatch. expected: %s - got: %s", relay.PublicKey.String(), bidInfo.pubkey.String())
-				return
-			}
+				// Skip if payload is empty
+				if responsePayload.IsEmpty() {
+					return
+				}
 
-			// Verify the relay signature in the relay response
-			if !config.SkipRelaySignatureCheck {
-				ok, err := checkRelaySignature(responsePayload, m.builderSigningDomain, relay.PublicKey)
+				// Getting the bid info will check if there are missing fields in the response
+				bidInfo, err := parseBidInfo(responsePayload)
 				if err != nil {
-					log.WithError(err).Error("error verifying relay signature")
+					log.WithError(err).Warn("error parsing bid info")
+					recordRelayError(relay, methodGetHeader, errorClassInvalid)
 					return
 				}
-				if !ok {
-					log.Error("failed to verify relay signature")
+
+				if bidInfo.blockHash == nilHash {
+					log.Warn("relay responded with empty block hash")
+					recordRelayError(relay, methodGetHeader, errorClassInvalid)
 					return
 				}
-			}
-
-			// Verify response coherence with proposer's input data
-			if bidInfo.parentHash.String() != parentHashHex {
-				log.WithFields(logrus.Fields{
-					"originalParentHash": parentHashHex,
-					"responseParentHash": bidInfo.parentHash.String(),
-				}).Error("proposer and relay parent hashes are not the same")
-				return
-			}
 
-			isZeroValue := bidInfo.value.IsZero()
-			isEmptyListTxRoot := bidInfo.txRoot.String() == "0x7ffe241ea60187fdb0187bfa22de35d1f9bed7ab061d9401fd47e34a54fbede1"
-			if isZeroValue || isEmptyListTxRoot {
-				log.Warn("ignoring bid with 0 value")
-				return
-			}
-			log.Debug("bid received")
+				// Ensure the bid is for the fork active at the requested slot
+				if fork, ok := m.versionAtSlot(_slot); ok && bidInfo.version != fork {
+					log.WithFields(logrus.Fields{
+						"bidVersion":  bidInfo.version.String(),
+						"slotVersion": fork.String(),
+					}).Warn("bid version does not match the fork at the slot")
+					recordRelayError(relay, methodGetHeader, errorClassInvalid)
+					return
+				}
 
-			// Skip if value (fee) is lower than the minimum bid
-			if bidInfo.value.CmpBig(m.relayMinBid.BigInt()) == -1 {
-				log.Debug("ignoring bid below min-bid value")
-				return
-			}
+				valueEth := weiBigIntToEthBigFloat(bidInfo.value.ToBig())
+				log = log.WithFields(logrus.Fields{
+					"blockNumber": bidInfo.blockNumber,
+					"blockHash":   bidInfo.blockHash.String(),
+					"txRoot":      bidInfo.txRoot.String(),
+					"value":       valueEth.Text('f', 18),
+				})
+
+				// Bids are selected by their value adjusted for the relay, the relay's signed bid is returned as is
+				health, _ := m.relayHealth.get(relay)
+				candidate := BidCandidate{
+					Bid:           responsePayload,
+					BlockHash:     bidInfo.blockHash,
+					ExtraData:     bidInfo.extraData,
+					ReceivedAt:    time.Now(),
+					Value:         bidInfo.value.ToBig(),
+					AdjustedValue: relay.AdjustBid(bidInfo.value.ToBig()),
+					Relay:         relay,
+					RelayPriority: priority,
+					RelayHealth:   health,
+					bidInfo:       bidInfo,
+				}
+				if candidate.AdjustedValue.Cmp(candidate.Value) != 0 {
+					log = log.WithField("adjustedValue", weiBigIntToEthBigFloat(candidate.AdjustedValue).Text('f', 18))
+				}
 
-			mu.Lock()
-			defer mu.Unlock()
+				// Run the validity checks, then the min-bid check and the configured filters
+				if filter := rejectingBidFilter(validityFilters, filterRequest, &candidate, log); filter != nil {
+					if c, ok := filter.(bidErrorClass); ok {
+						recordRelayError(relay, methodGetHeader, c.errorClass())
+					}
+					return
+				}
+				log.Debug("bid received")
+				bidsReceivedTotal.WithLabelValues(relayLabel(relay)).Inc()
+				if filter := rejectingBidFilter(policyFilters, filterRequest, &candidate, log); filter != nil {
+					bidsFilteredTotal.WithLabelValues(relayLabel(relay), filter.Name()).Inc()
+					return
+				}
 
-			// Remember which relays delivered which bids (multiple relays might deliver the top bid)
-			relays[BlockHashHex(bidInfo.blockHash.String())] = append(relays[BlockHashHex(bidInfo.blockHash.String())], relay)
+				mu.Lock()
+				defer mu.Unlock()
 
-			// Compare the bid with already known top bid (if any)
-			if !result.response.IsEmpty() {
-				valueDiff := bidInfo.value.Cmp(result.bidInfo.value)
-				if valueDiff == -1 { // current bid is less profitable than already known one
-					return
-				} else if valueDiff == 0 { // current bid is equally profitable as already known one. Use hash as tiebreaker
-					previousBidBlockHash := result.bidInfo.blockHash
-					if bidInfo.blockHash.String() >= previousBidBlockHash.String() {
-						return
-					}
+				// Remember which relays delivered which bids (multiple relays might deliver the top bid). With timing
+				// games, a relay may deliver the same bid several times.
+				blockHash := BlockHashHex(bidInfo.blockHash.String())
+				if !slices.ContainsFunc(relays[blockHash], func(r types.RelayEntry) bool { return r.String() == relay.String() }) {
+					relays[blockHash] = append(relays[blockHash], relay)
 				}
-			}
 
-			// Use this relay's response as mev-boost response because it's most profitable
-			log.Debug("new best bid")
-			result.response = *responsePayload
-			result.bidInfo = bidInfo
-			result.t = time.Now()
-		}(relay)
+				candidates = append(candidates, candidate)
+			}(priority, relay)
+		}
+		// Wait for all requests to complete...
+		wg.Wait()
+
+		nextPoll := pollStart.Add(settings.TimingGamesPollInterval)
+		if !settings.TimingGames || !nextPoll.Before(pollDeadline) {
+			break
+		}
+		select {
+		case <-req.Context().Done():
+		case <-time.After(time.Until(nextPoll)):
+		}
+		if req.Context().Err() != nil {
+			break
+		}
+	}
+
+	if req.Context().Err() != nil {
+		log.Info("getHeader request cancelled, the beacon node went away")
+		return
 	}
-	// Wait for all requests to complete...
-	wg.Wait()
 
-	if result.response.IsEmpty() {
+	if len(candidates) == 0 {
 		log.Info("no bid received")
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	// Drop the bids with an inconsistent block number or ignoring the gas limit registered by the proposer, then
+	// select the bid to return
+	candidates = m.checkBlockNumbers(log, filterRequest, candidates)
+	candidates = m.checkGasLimits(log, filterRequest, candidates)
+	selected := m.bidSelector.SelectBid(candidates)
+	if selected < 0 || selected >= len(candidates) {
+		log.WithField("numBids", len(candidates)).Info("no bid selected")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	winner := candidates[selected]
+	result := bidResp{t: winner.ReceivedAt, response: *winner.Bid, bidInfo: winner.bidInfo}
+
 	// Log result
 	valueEth := weiBigIntToEthBigFloat(result.bidInfo.value.ToBig())
 	result.relays = relays[BlockHashHex(result.bidInfo.blockHash.String())]
@@ -480,25 +835,59 @@ func (m *BoostService) handleGetHeader(w http.ResponseWriter, req *http.Request)
 		"value":       valueEth.Text('f', 18),
 		"relays":      strings.Join(types.RelayEntriesToStrings(result.relays), ", "),
 	}).Info("best bid")
+	for _, relay := range result.relays {
+		bidsWonTotal.WithLabelValues(relayLabel(relay)).Inc()
+	}
 
 	// Remember the bid, for future logging in case of withholding
 	bidKey := bidRespKey{slot: _slot, blockHash: result.bidInfo.blockHash.String()}
-	m.bidsLock.Lock()
-	m.bids[bidKey] = result
-	m.bidsLock.Unlock()
+	m.bids.put(bidKey, result)
+	if m.bidStore != nil {
+		if err := m.bidStore.put(newBidRecord(bidKey, result)); err != nil {
+			log.WithError(err).Warn("could not save the bid to the bid store")
+		}
+	}
 
 	// Return the bid
-	m.respondOK(w, &result.response)
+	m.respondVersioned(w, mediaType, result.response.Version, &result.response, func() ([]byte, error) {
+		return signedBuilderBidSSZ(&result.response)
+	})
 }
 
-func (m *BoostService) processDenebPayload(w http.ResponseWriter, req *http.Request, log *logrus.Entry, blindedBlock *eth2ApiV1Deneb.SignedBlindedBeaconBlock) {
+func (m *BoostService) processPayload(w http.ResponseWriter, req *http.Request, log *logrus.Entry, mediaType string, blindedBlock *eth2Api.VersionedSignedBlindedBeaconBlock) {
+	request, header, commitments, err := blindedBlockRequest(blindedBlock)
+	if err != nil {
+		log.WithError(err).Error("invalid signed blinded beacon block")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	slot, err := blindedBlock.Slot()
+	if err != nil {
+		log.WithError(err).Error("invalid signed blinded beacon block")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	parentBeaconRoot, err := blindedBlock.ParentRoot()
+	if err != nil {
+		log.WithError(err).Error("invalid signed blinded beacon block")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var executionRequests *electra.ExecutionRequests
+	if blindedBlock.Version == spec.DataVersionElectra {
+		executionRequests = blindedBlock.Electra.Message.Body.ExecutionRequests
+		if executionRequests == nil {
+			executionRequests = new(electra.ExecutionRequests)
+		}
+	}
+
 	// Get the currentSlotUID for this slot
 	currentSlotUID := ""
 	m.slotUIDLock.Lock()
-	if m.slotUID.slot == uint64(blindedBlock.Message.Slot) {
+	if m.slotUID.slot == uint64(slot) {
 		currentSlotUID = m.slotUID.uid.String()
 	} else {
-		log.Warnf("latest slotUID is for slot %d rather than payload slot %d", m.slotUID.slot, blindedBlock.Message.Slot)
+		log.Warnf("latest slotUID is for slot %d rather than payload slot %d", m.slotUID.slot, slot)
 	}
 	m.slotUIDLock.Unlock()
 
@@ -506,85 +895,192 @@ func (m *BoostService) processDenebPayload(w http.ResponseWriter, req *http.Requ
 	ua := UserAgent(req.Header.Get("User-Agent"))
 	log = log.WithFields(logrus.Fields{
 		"ua":         ua,
-		"slot":       blindedBlock.Message.Slot,
-		"blockHash":  blindedBlock.Message.Body.ExecutionPayloadHeader.BlockHash.String(),
-		"parentHash": blindedBlock.Message.Body.ExecutionPayloadHeader.ParentHash.String(),
+		"slot":       slot,
+		"version":    blindedBlock.Version.String(),
+		"blockHash":  header.BlockHash.String(),
+		"parentHash": header.ParentHash.String(),
 		"slotUID":    currentSlotUID,
 	})
 
 	// Log how late into the slot the request starts
-	slotStartTimestamp := m.genesisTime + uint64(blindedBlock.Message.Slot)*config.SlotTimeSec
-	msIntoSlot := uint64(time.Now().UTC().UnixMilli()) - slotStartTimestamp*1000
+	slotStartTimestamp := m.genesisTime + uint64(slot)*config.SlotTimeSec
+	msIntoSlot := time.Now().UTC().UnixMilli() - int64(slotStartTimestamp)*1000
 	log.WithFields(logrus.Fields{
 		"genesisTime": m.genesisTime,
 		"slotTimeSec": config.SlotTimeSec,
 		"msIntoSlot":  msIntoSlot,
-	}).Infof("submitBlindedBlock request start - %d milliseconds into slot %d", msIntoSlot, blindedBlock.Message.Slot)
-
-	// Get the bid!
-	bidKey := bidRespKey{slot: uint64(blindedBlock.Message.Slot), blockHash: blindedBlock.Message.Body.ExecutionPayloadHeader.BlockHash.String()}
-	m.bidsLock.Lock()
-	originalBid := m.bids[bidKey]
-	m.bidsLock.Unlock()
+	}).Infof("submitBlindedBlock request start - %d milliseconds into slot %d", msIntoSlot, slot)
+	m.recordMsIntoSlot(methodGetPayload, msIntoSlot)
+
+	// Get the bid! If it's not in memory, mev-boost may have restarted since getHeader
+	settings := m.runtimeSettings()
+	bidKey := bidRespKey{slot: uint64(slot), blockHash: header.BlockHash.String()}
+	originalBid, ok := m.bids.get(bidKey)
+	if !ok {
+		if storedBid, ok := m.storedBid(bidKey, settings.Relays); ok {
+			log.Info("bid found in the bid store")
+			originalBid = storedBid
+		}
+	}
 	if originalBid.response.IsEmpty() {
 		log.Error("no bid for this getPayload payload found, was getHeader called before?")
 	} else if len(originalBid.relays) == 0 {
 		log.Warn("bid found but no associated relays")
 	}
+	if len(originalBid.relays) == 0 && m.payloadFallback == PayloadFallbackNone {
+		log.Error("not sending the block to any relay, as no relay is known to have offered its bid")
+		m.respondError(w, http.StatusBadRequest, errNoBidRelays.Error())
+		return
+	}
+
+	// Ensure the execution requests of the block are the ones committed to by the bid
+	if blindedBlock.Version == spec.DataVersionElectra && !originalBid.response.IsEmpty() {
+		if err := checkExecutionRequests(blindedBlock.Electra, &originalBid.response); err != nil {
+			log.WithError(err).Error("invalid execution requests in signed blinded beacon block")
+			m.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	// Never reveal two different blocks for the same slot to the relays, which would get the proposer slashed
+	proposerIndex, err := blindedBlock.ProposerIndex()
+	if err != nil {
+		log.WithError(err).Error("invalid signed blinded beacon block")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	blockRoot, err := blindedBlock.Root()
+	if err != nil {
+		log.WithError(err).Error("invalid signed blinded beacon block")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	duplicate, err := m.signedBlocks.record(uint64(slot), uint64(proposerIndex), blockRoot)
+	if errors.Is(err, errEquivocation) {
+		log.WithError(err).WithField("blockRoot", blockRoot.String()).Error("refusing a different block for an already submitted slot")
+		m.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	} else if err != nil {
+		log.WithError(err).Error("could not record the signed blinded beacon block")
+		m.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	} else if duplicate {
+		log.Info("block already submitted for this slot, submitting it again")
+	}
 
 	// Add request headers
 	headers := map[string]string{
-		HeaderKeySlotUID:      currentSlotUID,
-		HeaderStartTimeUnixMS: fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
+		HeaderKeySlotUID:          currentSlotUID,
+		HeaderStartTimeUnixMS:     fmt.Sprintf("%d", time.Now().UTC().UnixMilli()),
+		HeaderEthConsensusVersion: blindedBlock.Version.String(),
+	}
+
+	// Only send the signed block to the relays which provided the bid, including relays which have been removed
+	// since, whatever the state of their circuit breaker. Other relays can't deliver the payload. Without a known
+	// bid, fall back to all relays.
+	relays := originalBid.relays
+	if len(relays) == 0 {
+		relays = settings.Relays
+	}
+
+	// The block is signed already, so relays are still asked for the payload if the deadline has passed
+	deadline := m.slotTime(uint64(slot), settings.GetPayloadDeadline)
+	if !deadline.IsZero() && !time.Now().Before(deadline) {
+		log.Warn("getPayload request past the deadline, using the request timeout")
+		deadline = time.Time{}
 	}
 
 	// Prepare for requests
-	resultCh := make(chan *builderApi.VersionedSubmitBlindedBlockResponse, len(m.relays))
+	resultCh := make(chan *builderApi.VersionedSubmitBlindedBlockResponse, len(relays))
 	var received atomic.Bool
 	go func() {
-		// Make sure we receive a response within the timeout
-		time.Sleep(m.httpClientGetPayload.Timeout)
+		// Make sure we receive a response within the timeout, allowing for relays with a longer timeout override
+		timeout := settings.RequestTimeoutGetPayload
+		for _, relay := range relays {
+			if relay.TimeoutGetPayload > timeout {
+				timeout = relay.TimeoutGetPayload
+			}
+		}
+		if remaining := time.Until(deadline); !deadline.IsZero() && remaining < timeout {
+			timeout = remaining
+		}
+		time.Sleep(timeout)
 		resultCh <- nil
 	}()
 
-	// Prepare the request context, which will be cancelled after the first successful response from a relay
-	requestCtx, requestCtxCancel := context.WithCancel(context.Background())
+	// Prepare the request context, which will be cancelled after the first successful response from a relay, or
+	// if the beacon node goes away
+	requestCtx, requestCtxCancel := context.WithCancel(req.Context())
 	defer requestCtxCancel()
 
-	for _, relay := range m.relays {
+	for _, relay := range relays {
 		go func(relay types.RelayEntry) {
 			url := relay.GetURI(params.PathGetPayload)
 			log := log.WithField("url", url)
 			log.Debug("calling getPayload")
 
+			client := relayHTTPClient(settings.RequestTimeoutGetPayload, relay.TimeoutGetPayload)
+			capTimeout(&client, deadline)
+			maxRetries := settings.RequestMaxRetries
+			if relay.MaxRetries > 0 {
+				maxRetries = relay.MaxRetries
+			}
+
 			responsePayload := new(builderApi.VersionedSubmitBlindedBlockResponse)
-			_, err := SendHTTPRequestWithRetries(requestCtx, m.httpClientGetPayload, http.MethodPost, url, ua, headers, blindedBlock, responsePayload, m.requestMaxRetries, log)
+			start := time.Now()
+			_, err := m.sendRelayRequest(relay, relayRequestHeaders(relay, headers), request, &submitBlindedBlockResponse{VersionedSubmitBlindedBlockResponse: responsePayload}, log,
+				func(headers map[string]string, payload, dst any) (int, error) {
+					return SendHTTPRequestWithRetries(requestCtx, client, http.MethodPost, url, ua, headers, payload, dst, maxRetries, log)
+				})
 			if err != nil {
-				if errors.Is(requestCtx.Err(), context.Canceled) {
+				if req.Context().Err() != nil {
+					log.Info("request cancelled, the beacon node went away")
+					recordRelayRequest(relay, methodGetPayload, start, err)
+				} else if errors.Is(requestCtx.Err(), context.Canceled) {
 					log.Info("request was cancelled") // this is expected, if payload has already been received by another relay
 				} else {
 					log.WithError(err).Error("error making request to relay")
+					recordRelayRequest(relay, methodGetPayload, start, err)
 				}
 				return
 			}
+			recordRelayRequest(relay, methodGetPayload, start, nil)
 
 			if getPayloadResponseIsEmpty(responsePayload) {
 				log.Error("response with empty data!")
+				recordRelayError(relay, methodGetPayload, errorClassInvalid)
+				return
+			}
+
+			// Ensure the response version matches the request
+			if responsePayload.Version != blindedBlock.Version {
+				log.WithFields(logrus.Fields{
+					"responseVersion": responsePayload.Version.String(),
+				}).Error("response version does not match request version")
+				recordRelayError(relay, methodGetPayload, errorClassInvalid)
 				return
 			}
 
-			payload := responsePayload.Deneb.ExecutionPayload
-			blobs := responsePayload.Deneb.BlobsBundle
+			bundle := getPayloadResponseBundle(responsePayload)
+			payload := bundle.ExecutionPayload
+			blobs := bundle.BlobsBundle
 
 			// Ensure the response blockhash matches the request
-			if blindedBlock.Message.Body.ExecutionPayloadHeader.BlockHash != payload.BlockHash {
+			if header.BlockHash != payload.BlockHash {
 				log.WithFields(logrus.Fields{
 					"responseBlockHash": payload.BlockHash.String(),
 				}).Error("requestBlockHash does not equal responseBlockHash")
+				recordRelayError(relay, methodGetPayload, errorClassInvalid)
+				return
+			}
+
+			// Ensure the payload is the one the proposer signed the header of
+			if err := checkExecutionPayload(payload, header, parentBeaconRoot, executionRequests); err != nil {
+				log.WithError(err).Error("invalid execution payload")
+				recordRelayError(relay, methodGetPayload, errorClassInvalid)
 				return
 			}
 
-			commitments := blindedBlock.Message.Body.BlobKZGCommitments
 			// Ensure that blobs are valid and matches the request
 			if len(commitments) != len(blobs.Blobs) || len(commitments) != len(blobs.Commitments) || len(commitments) != len(blobs.Proofs) {
 				log.WithFields(logrus.Fields{
@@ -593,6 +1089,7 @@ func (m *BoostService) processDenebPayload(w http.ResponseWriter, req *http.Requ
 					"responseBlobCommitments": len(blobs.Commitments),
 					"responseBlobProofs":      len(blobs.Proofs),
 				}).Error("block KZG commitment length does not equal responseBlobs length")
+				recordRelayError(relay, methodGetPayload, errorClassInvalid)
 				return
 			}
 
@@ -603,10 +1100,24 @@ func (m *BoostService) processDenebPayload(w http.ResponseWriter, req *http.Requ
 						"responseBlobCommitment": blobs.Commitments[i].String(),
 						"index":                  i,
 					}).Error("requestBlobCommitment does not equal responseBlobCommitment")
+					recordRelayError(relay, methodGetPayload, errorClassInvalid)
 					return
 				}
 			}
 
+			if m.verifyBlobs {
+				start := time.Now()
+				if err := verifyBlobsBundle(blobs); err != nil {
+					log.WithError(err).Error("invalid blobs")
+					recordRelayError(relay, methodGetPayload, errorClassInvalid)
+					return
+				}
+				log.WithFields(logrus.Fields{
+					"numBlobs":   len(blobs.Blobs),
+					"durationMs": time.Since(start).Milliseconds(),
+				}).Debug("verified blob KZG proofs")
+			}
+
 			requestCtxCancel()
 			if received.CompareAndSwap(false, true) {
 				resultCh <- responsePayload
@@ -618,23 +1129,45 @@ func (m *BoostService) processDenebPayload(w http.ResponseWriter, req *http.Requ
 	}
 
 	// Wait for the first request to complete
-	result := <-resultCh
+	var result *builderApi.VersionedSubmitBlindedBlockResponse
+	select {
+	case result = <-resultCh:
+	case <-req.Context().Done():
+		log.Warn("getPayload request cancelled, the beacon node went away")
+		return
+	}
 
 	// If no payload has been received from relay, log loudly about withholding!
 	if result == nil || getPayloadResponseIsEmpty(result) {
 		originRelays := types.RelayEntriesToStrings(originalBid.relays)
 		log.WithField("relaysWithBid", strings.Join(originRelays, ", ")).Error("no payload received from relay!")
+		for _, relay := range originalBid.relays {
+			payloadWithheldTotal.WithLabelValues(relayLabel(relay)).Inc()
+		}
 		m.respondError(w, http.StatusBadGateway, errNoSuccessfulRelayResponse.Error())
 		return
 	}
 
-	m.respondOK(w, result)
+	m.respondVersioned(w, mediaType, result.Version, result, func() ([]byte, error) {
+		return getPayloadResponseSSZ(result)
+	})
 }
 
 func (m *BoostService) handleGetPayload(w http.ResponseWriter, req *http.Request) {
 	log := m.log.WithField("method", "getPayload")
 	log.Debug("getPayload request starts")
 
+	sszEncoded, err := requestIsSSZ(req)
+	if err != nil {
+		m.respondError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+	mediaType, ok := negotiateMediaType(req.Header.Get("Accept"))
+	if !ok {
+		m.respondError(w, http.StatusNotAcceptable, errNotAcceptable.Error())
+		return
+	}
+
 	// Read the body first, so we can log it later on error
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
@@ -643,22 +1176,33 @@ func (m *BoostService) handleGetPayload(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	// Decode the body now
-	payload := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
-	if err := DecodeJSON(bytes.NewReader(body), payload); err != nil {
-		log.WithError(err).WithField("body", string(body)).Error("could not decode request payload from the beacon-node (signed blinded beacon block)")
+	// Decode the body now, using the fork version sent by the beacon node or else the fork of the slot
+	payload, err := decodeSignedBlindedBeaconBlock(body, sszEncoded, req.Header.Get(HeaderEthConsensusVersion), m.forkSchedule)
+	if err != nil {
+		logBody := string(body)
+		if sszEncoded {
+			logBody = fmt.Sprintf("%#x", body)
+		}
+		log.WithError(err).WithField("body", logBody).Error("could not decode request payload from the beacon-node (signed blinded beacon block)")
 		m.respondError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	m.processDenebPayload(w, req, log, payload)
+	m.processPayload(w, req, log, mediaType, payload)
 }
 
-// CheckRelays sends a request to each one of the relays previously registered to get their status
-func (m *BoostService) CheckRelays() int {
+// CheckRelays sends a request to each one of the relays previously registered to get their status, and records
+// the results in the relay health cache. Pending requests are cancelled with ctx.
+func (m *BoostService) CheckRelays(ctx context.Context) int {
+	settings := m.runtimeSettings()
+	return m.checkRelays(ctx, settings, settings.Relays)
+}
+
+// checkRelays checks the status of the given relays, returning the number of relays whose status is OK
+func (m *BoostService) checkRelays(ctx context.Context, settings RuntimeSettings, relays []types.RelayEntry) int {
 	var wg sync.WaitGroup
 	var numSuccessRequestsToRelay uint32
 
-	for _, r := range m.relays {
+	for _, r := range relays {
 		wg.Add(1)
 
 		go func(relay types.RelayEntry) {
@@ -667,15 +1211,28 @@ func (m *BoostService) CheckRelays() int {
 			log := m.log.WithField("url", url)
 			log.Debug("checking relay status")
 
-			code, err := SendHTTPRequest(context.Background(), m.httpClientGetHeader, http.MethodGet, url, "", nil, nil, nil)
-			if err != nil {
+			client := relayHTTPClient(settings.RequestTimeoutGetHeader, relay.TimeoutGetHeader)
+			start := time.Now()
+			code, err := SendHTTPRequest(ctx, client, http.MethodGet, url, "", relayRequestHeaders(relay, nil), nil, nil)
+			recordRelayRequest(relay, methodStatus, start, err)
+			if errors.Is(err, context.Canceled) {
+				log.Info("relay status request cancelled")
+				return
+			} else if err != nil {
 				log.WithError(err).Error("relay status error - request failed")
+				m.relayHealth.record(relay, false, 0)
+				m.relayBreakers.record(relay, false)
 				return
 			}
 			if code == http.StatusOK {
 				log.Debug("relay status OK")
+				m.relayHealth.record(relay, true, time.Since(start))
+				m.relayBreakers.record(relay, true)
 			} else {
 				log.Errorf("relay status error - unexpected status code %d", code)
+				recordRelayError(relay, methodStatus, errorClassHTTPStatus)
+				m.relayHealth.record(relay, false, 0)
+				m.relayBreakers.record(relay, false)
 				return
 			}
 
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -19,16 +20,21 @@ import (
 	builderApiV1 "github.com/attestantio/go-builder-client/api/v1"
 	builderSpec "github.com/attestantio/go-builder-client/spec"
 	eth2ApiV1Deneb "github.com/attestantio/go-eth2-client/api/v1/deneb"
+	eth2ApiV1Electra "github.com/attestantio/go-eth2-client/api/v1/electra"
 	"github.com/attestantio/go-eth2-client/spec"
 	"github.com/attestantio/go-eth2-client/spec/altair"
 	"github.com/attestantio/go-eth2-client/spec/bellatrix"
 	"github.com/attestantio/go-eth2-client/spec/capella"
 	"github.com/attestantio/go-eth2-client/spec/deneb"
+	"github.com/attestantio/go-eth2-client/spec/electra"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	eth2UtilBellatrix "github.com/attestantio/go-eth2-client/util/bellatrix"
+	eth2UtilCapella "github.com/attestantio/go-eth2-client/util/capella"
+	"github.com/flashbots/mev-boost/config"
 	"github.com/flashbots/mev-boost/server/mock"
 	"github.com/flashbots/mev-boost/server/types"
 	"github.com/holiman/uint256"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prysmaticlabs/go-bitfield"
 	"github.com/stretchr/testify/require"
 )
@@ -66,11 +72,19 @@ func newTestBackend(t *testing.T, numRelays int, relayTimeout time.Duration) *te
 	}
 	service, err := NewBoostService(opts)
 	require.NoError(t, err)
+	t.Cleanup(func() { require.NoError(t, service.Close()) })
 
 	backend.boost = service
 	return &backend
 }
 
+// setSlot makes the headers of the relays have the timestamp of the given slot, which is slot 1 by default
+func (be *testBackend) setSlot(slot uint64) {
+	for _, relay := range be.relays {
+		relay.HeaderTimestamp = be.boost.genesisTime + slot*config.SlotTimeSec
+	}
+}
+
 func (be *testBackend) request(t *testing.T, method, path string, payload any) *httptest.ResponseRecorder {
 	t.Helper()
 	var req *http.Request
@@ -90,11 +104,61 @@ func (be *testBackend) request(t *testing.T, method, path string, payload any) *
 	return rr
 }
 
+// requestWithHeaders sends a request with a raw body and custom headers, eg. to test SSZ encoded requests
+func (be *testBackend) requestWithHeaders(t *testing.T, method, path string, body []byte, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	require.NoError(t, err)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rr := httptest.NewRecorder()
+	be.boost.getRouter().ServeHTTP(rr, req)
+	return rr
+}
+
 func blindedBlockContentsToPayloadDeneb(signedBlindedBlockContents *eth2ApiV1Deneb.SignedBlindedBeaconBlock) *builderApiDeneb.ExecutionPayloadAndBlobsBundle {
-	header := signedBlindedBlockContents.Message.Body.ExecutionPayloadHeader
-	numBlobs := len(signedBlindedBlockContents.Message.Body.BlobKZGCommitments)
+	body := signedBlindedBlockContents.Message.Body
+	return executionPayloadHeaderToPayload(body.ExecutionPayloadHeader, body.BlobKZGCommitments)
+}
+
+func blindedBlockContentsToPayloadElectra(signedBlindedBlockContents *eth2ApiV1Electra.SignedBlindedBeaconBlock) *builderApiDeneb.ExecutionPayloadAndBlobsBundle {
+	body := signedBlindedBlockContents.Message.Body
+	return executionPayloadHeaderToPayload(body.ExecutionPayloadHeader, body.BlobKZGCommitments)
+}
+
+// sealBlindedBlockDeneb makes the execution payload header of a blinded block the header of the payload returned by
+// blindedBlockContentsToPayloadDeneb, so that getPayload accepts the payload
+func sealBlindedBlockDeneb(t *testing.T, block *eth2ApiV1Deneb.SignedBlindedBeaconBlock) {
+	t.Helper()
+	payload := blindedBlockContentsToPayloadDeneb(block).ExecutionPayload
+	sealPayload(t, payload, block.Message.Body.ExecutionPayloadHeader, block.Message.ParentRoot, nil)
+}
+
+// sealBlindedBlockElectra is sealBlindedBlockDeneb for electra blocks
+func sealBlindedBlockElectra(t *testing.T, block *eth2ApiV1Electra.SignedBlindedBeaconBlock) {
+	t.Helper()
+	payload := blindedBlockContentsToPayloadElectra(block).ExecutionPayload
+	sealPayload(t, payload, block.Message.Body.ExecutionPayloadHeader, block.Message.ParentRoot, block.Message.Body.ExecutionRequests)
+}
+
+// sealPayload sets the block hash of a payload, and the block hash and roots of a header, to the ones of the payload
+func sealPayload(t *testing.T, payload *deneb.ExecutionPayload, header *deneb.ExecutionPayloadHeader, parentBeaconRoot phase0.Root, requests *electra.ExecutionRequests) {
+	t.Helper()
+	txRoot, err := (&eth2UtilBellatrix.ExecutionPayloadTransactions{Transactions: payload.Transactions}).HashTreeRoot()
+	require.NoError(t, err)
+	withdrawalsRoot, err := (&eth2UtilCapella.ExecutionPayloadWithdrawals{Withdrawals: payload.Withdrawals}).HashTreeRoot()
+	require.NoError(t, err)
+	blockHash, err := computeBlockHash(payload, parentBeaconRoot, requests)
+	require.NoError(t, err)
+	header.TransactionsRoot, header.WithdrawalsRoot = txRoot, withdrawalsRoot
+	payload.BlockHash, header.BlockHash = blockHash, blockHash
+}
+
+func executionPayloadHeaderToPayload(header *deneb.ExecutionPayloadHeader, blobKZGCommitments []deneb.KZGCommitment) *builderApiDeneb.ExecutionPayloadAndBlobsBundle {
+	numBlobs := len(blobKZGCommitments)
 	commitments := make([]deneb.KZGCommitment, numBlobs)
-	copy(commitments, signedBlindedBlockContents.Message.Body.BlobKZGCommitments)
+	copy(commitments, blobKZGCommitments)
 	proofs := make([]deneb.KZGProof, numBlobs)
 	blobs := make([]deneb.Blob, numBlobs)
 	return &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
@@ -143,6 +207,16 @@ func TestNewBoostServiceErrors(t *testing.T) {
 		})
 		require.Error(t, err)
 	})
+
+	t.Run("errors with an unknown payload fallback", func(t *testing.T) {
+		_, err := NewBoostService(BoostServiceOpts{
+			Log:                   mock.TestLog,
+			Relays:                []types.RelayEntry{mock.NewRelay(t).RelayEntry},
+			GenesisForkVersionHex: "0x00000000",
+			PayloadFallbackRelays: "some",
+		})
+		require.ErrorIs(t, err, errUnknownPayloadFallback)
+	})
 }
 
 func TestWebserver(t *testing.T) {
@@ -322,6 +396,66 @@ func TestGetHeader(t *testing.T) {
 		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
 	})
 
+	t.Run("Okay response from relay electra", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		resp := backend.relays[0].MakeGetHeaderResponse(
+			12345,
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionElectra,
+		)
+		backend.relays[0].GetHeaderResponse = resp
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+
+		bid := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		require.Equal(t, spec.DataVersionElectra, bid.Version)
+		require.NotNil(t, bid.Electra.Message.ExecutionRequests)
+	})
+
+	t.Run("Bid version does not match the fork at the slot", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		schedule, err := types.NewForkSchedule([]types.Fork{
+			{Version: spec.DataVersionDeneb, Epoch: 0},
+			{Version: spec.DataVersionElectra, Epoch: 0},
+		})
+		require.NoError(t, err)
+		backend.boost.forkSchedule = schedule
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("SSZ response", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		rr := backend.requestWithHeaders(t, http.MethodGet, path, nil, map[string]string{"Accept": "application/octet-stream;q=1.0,application/json;q=0.9"})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, MediaTypeOctetStream, rr.Header().Get("Content-Type"))
+		require.Equal(t, "deneb", rr.Header().Get(HeaderEthConsensusVersion))
+
+		bid := new(builderApiDeneb.SignedBuilderBid)
+		require.NoError(t, bid.UnmarshalSSZ(rr.Body.Bytes()))
+		require.Equal(t, hash, bid.Message.Header.BlockHash)
+	})
+
+	t.Run("JSON response with consensus version header", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, MediaTypeJSON, rr.Header().Get("Content-Type"))
+		require.Equal(t, "deneb", rr.Header().Get(HeaderEthConsensusVersion))
+	})
+
+	t.Run("Not acceptable media type", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		rr := backend.requestWithHeaders(t, http.MethodGet, path, nil, map[string]string{"Accept": "text/html"})
+		require.Equal(t, http.StatusNotAcceptable, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+	})
+
 	t.Run("Bad response from relays", func(t *testing.T) {
 		backend := newTestBackend(t, 2, time.Second)
 		resp := backend.relays[0].MakeGetHeaderResponse(
@@ -348,6 +482,26 @@ func TestGetHeader(t *testing.T) {
 		require.Equal(t, http.StatusNoContent, rr.Code)
 	})
 
+	t.Run("Relay custom headers are sent", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.relays[0].Headers = map[string]string{
+			"X-Api-Key":      "secret",
+			HeaderKeySlotUID: "overridden",
+		}
+
+		var receivedAPIKey, receivedSlotUID string
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+			receivedAPIKey = req.Header.Get("X-Api-Key")
+			receivedSlotUID = req.Header.Get(HeaderKeySlotUID)
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		require.Equal(t, "secret", receivedAPIKey)
+		require.NotEqual(t, "overridden", receivedSlotUID)
+	})
+
 	t.Run("Invalid relay public key", func(t *testing.T) {
 		backend := newTestBackend(t, 1, time.Second)
 
@@ -443,6 +597,7 @@ func TestGetHeaderBids(t *testing.T) {
 	t.Run("Use header with highest value", func(t *testing.T) {
 		// Create backend and register 3 relays.
 		backend := newTestBackend(t, 3, time.Second)
+		backend.setSlot(2)
 
 		// First relay will return signed response with value 12345.
 		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
@@ -490,9 +645,38 @@ func TestGetHeaderBids(t *testing.T) {
 		require.Equal(t, uint256.NewInt(12347), value)
 	})
 
+	t.Run("Use header with highest adjusted value", func(t *testing.T) {
+		backend := newTestBackend(t, 3, time.Second)
+		backend.setSlot(2)
+		backend.boost.relays[1].BidMultiplier = 0.5
+		backend.boost.relays[2].BidAdjustment = big.NewInt(1000)
+
+		for i, value := range []uint64{13000, 20000, 12500} {
+			backend.relays[i].GetHeaderResponse = backend.relays[i].MakeGetHeaderResponse(
+				value,
+				"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+				"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+				"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+				spec.DataVersionDeneb,
+			)
+		}
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// The third relay wins with an adjusted value of 13500, and its bid is returned unchanged
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		value, err := resp.Value()
+		require.NoError(t, err)
+		require.Equal(t, uint256.NewInt(12500), value)
+		require.Equal(t, backend.relays[2].GetHeaderResponse.Deneb.Signature, resp.Deneb.Signature)
+	})
+
 	t.Run("Use header with lowest blockhash if same value", func(t *testing.T) {
 		// Create backend and register 3 relays.
 		backend := newTestBackend(t, 3, time.Second)
+		backend.setSlot(2)
 
 		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
 			12345,
@@ -544,6 +728,7 @@ func TestGetHeaderBids(t *testing.T) {
 	t.Run("Respect minimum bid cutoff", func(t *testing.T) {
 		// Create backend and register relay.
 		backend := newTestBackend(t, 1, time.Second)
+		backend.setSlot(2)
 
 		// Relay will return signed response with value 12344.
 		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
@@ -564,9 +749,44 @@ func TestGetHeaderBids(t *testing.T) {
 		require.Equal(t, http.StatusNoContent, rr.Code)
 	})
 
+	t.Run("Respect per-relay minimum bid", func(t *testing.T) {
+		backend := newTestBackend(t, 2, time.Second)
+		backend.setSlot(2)
+
+		// First relay has a higher minimum bid than its bid value
+		minBid := types.IntToU256(12350)
+		backend.boost.relays[0].MinBid = &minBid
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12347,
+			"0xa18385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+		backend.relays[1].GetHeaderResponse = backend.relays[1].MakeGetHeaderResponse(
+			12346,
+			"0xa28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7",
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// The second relay wins, since the first relay's bid is below its minimum bid
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		err := json.Unmarshal(rr.Body.Bytes(), resp)
+		require.NoError(t, err)
+		value, err := resp.Value()
+		require.NoError(t, err)
+		require.Equal(t, uint256.NewInt(12346), value)
+	})
+
 	t.Run("Allow bids which meet minimum bid cutoff", func(t *testing.T) {
 		// Create backend and register relay.
 		backend := newTestBackend(t, 1, time.Second)
+		backend.setSlot(2)
 
 		// First relay will return signed response with value 12345.
 		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
@@ -593,6 +813,181 @@ func TestGetHeaderBids(t *testing.T) {
 	})
 }
 
+func TestGetHeaderTimingGames(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// newBackend returns a backend whose relay bids a higher value on each request, with slot 1 starting now
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - config.SlotTimeSec
+		backend.setSlot(1)
+		backend.boost.timingGames = true
+		backend.boost.timingGamesPollInterval = 50 * time.Millisecond
+
+		value := uint64(12345)
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			response := backend.relays[0].MakeGetHeaderResponse(value, hash.String(), hash.String(), pubkey.String(), spec.DataVersionDeneb)
+			value++
+			w.Header().Set("Content-Type", "application/json")
+			require.NoError(t, json.NewEncoder(w).Encode(response))
+		})
+		return backend
+	}
+
+	t.Run("Polls relays until the deadline and returns the best bid", func(t *testing.T) {
+		backend := newBackend(t)
+		slotStart := time.Unix(int64(backend.boost.genesisTime+config.SlotTimeSec), 0)
+		backend.boost.timingGamesDelay = time.Since(slotStart) + time.Second
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// Polled more than once, but not more often than the poll interval
+		count := backend.relays[0].GetRequestCount(path)
+		require.GreaterOrEqual(t, count, 2)
+		require.LessOrEqual(t, count, 21)
+
+		// A bid of a later poll is chosen over the first one. The last poll may be cut short by the deadline.
+		resp := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		value, err := resp.Value()
+		require.NoError(t, err)
+		require.Greater(t, value.Uint64(), uint64(12345))
+		require.LessOrEqual(t, value.Uint64(), uint64(12345+count-1))
+
+		// The relay delivering the same block several times is only recorded once
+		bid, ok := backend.boost.bids.get(bidRespKey{slot: 1, blockHash: hash.String()})
+		require.True(t, ok)
+		require.Len(t, bid.relays, 1)
+	})
+
+	t.Run("Polls once after the deadline", func(t *testing.T) {
+		backend := newBackend(t)
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Errors without a poll interval", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		err := backend.boost.UpdateSettings(RuntimeSettings{Relays: backend.boost.relays, TimingGames: true})
+		require.ErrorIs(t, err, errInvalidPollInterval)
+	})
+}
+
+func TestGetHeaderDeadlines(t *testing.T) {
+	hash := mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7")
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	path := getHeaderPath(1, hash, pubkey)
+
+	// newBackend returns a backend with slot 1 having started the given time ago
+	newBackend := func(t *testing.T, intoSlot time.Duration) (*testBackend, time.Time) {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		slotStart := time.Now().Add(-intoSlot)
+		backend.boost.genesisTime = uint64(slotStart.Unix()) - config.SlotTimeSec
+		backend.setSlot(1)
+		return backend, time.Unix(slotStart.Unix(), 0)
+	}
+
+	t.Run("Request past the cutoff", func(t *testing.T) {
+		backend, _ := newBackend(t, 2*time.Second)
+		backend.boost.getHeaderCutoff = time.Second
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Request past the deadline", func(t *testing.T) {
+		backend, _ := newBackend(t, 2*time.Second)
+		backend.boost.getHeaderDeadline = time.Second
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Deadlines ignored without a genesis time", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		backend.boost.getHeaderCutoff = time.Second
+		backend.boost.getHeaderDeadline = time.Second
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Request before the cutoff", func(t *testing.T) {
+		backend, slotStart := newBackend(t, 0)
+		backend.boost.getHeaderCutoff = time.Since(slotStart) + time.Second
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Beacon node timeout below the margin", func(t *testing.T) {
+		backend, _ := newBackend(t, 0)
+		rr := backend.requestWithHeaders(t, http.MethodGet, path, nil, map[string]string{HeaderTimeoutMs: "50"})
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+	})
+
+	t.Run("Beacon node timeout caps the request timeout", func(t *testing.T) {
+		backend, _ := newBackend(t, 0)
+		backend.relays[0].ResponseDelay = 500 * time.Millisecond
+
+		start := time.Now()
+		rr := backend.requestWithHeaders(t, http.MethodGet, path, nil, map[string]string{
+			HeaderTimeoutMs:        "300",
+			HeaderDateMilliseconds: fmt.Sprint(start.UnixMilli()),
+		})
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 450*time.Millisecond)
+	})
+
+	t.Run("Request time is sent to relays", func(t *testing.T) {
+		backend, _ := newBackend(t, 0)
+		var dateMs, startTimeMs string
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+			dateMs = req.Header.Get(HeaderDateMilliseconds)
+			startTimeMs = req.Header.Get(HeaderStartTimeUnixMS)
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.NotEmpty(t, dateMs)
+		require.Equal(t, startTimeMs, dateMs)
+	})
+
+	t.Run("Deadline caps the request timeout", func(t *testing.T) {
+		backend, slotStart := newBackend(t, 0)
+		backend.boost.getHeaderDeadline = time.Since(slotStart) + 100*time.Millisecond
+		backend.relays[0].ResponseDelay = 500 * time.Millisecond
+
+		start := time.Now()
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 450*time.Millisecond)
+	})
+
+	t.Run("Request cut off by the deadline is not a relay failure", func(t *testing.T) {
+		backend, slotStart := newBackend(t, 0)
+		backend.boost.relayBreakers = newRelayCircuitBreakers(1, time.Hour)
+		backend.boost.getHeaderDeadline = time.Since(slotStart) + 100*time.Millisecond
+		backend.relays[0].ResponseDelay = 500 * time.Millisecond
+		relay := relayLabel(backend.relays[0].RelayEntry)
+
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code, rr.Body.String())
+		require.Equal(t, breakerClosed, backend.boost.relayBreakers.state(backend.relays[0].RelayEntry))
+		require.InDelta(t, 1, testutil.ToFloat64(relayRequestsCutOffTotal.WithLabelValues(relay, methodGetHeader)), 0)
+		require.InDelta(t, 0, testutil.ToFloat64(relayErrorsTotal.WithLabelValues(relay, methodGetHeader, errorClassTimeout)), 0)
+	})
+}
+
 func TestGetPayload(t *testing.T) {
 	path := "/eth/v1/builder/blinded_blocks"
 
@@ -630,8 +1025,23 @@ func TestGetPayload(t *testing.T) {
 		},
 	}
 
+	// newBackend returns a backend whose relays return the payload of the signed header
+	sealBlindedBlockDeneb(t, payload)
+	response := &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionDeneb,
+		Deneb:   blindedBlockContentsToPayloadDeneb(payload),
+	}
+	newBackend := func(t *testing.T, numRelays int, relayTimeout time.Duration) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, numRelays, relayTimeout)
+		for _, relay := range backend.relays {
+			relay.GetPayloadResponse = response
+		}
+		return backend
+	}
+
 	t.Run("Okay response from relay", func(t *testing.T) {
-		backend := newTestBackend(t, 1, time.Second)
+		backend := newBackend(t, 1, time.Second)
 		rr := backend.request(t, http.MethodPost, path, payload)
 		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
 		require.Equal(t, 1, backend.relays[0].GetRequestCount(path))
@@ -642,8 +1052,29 @@ func TestGetPayload(t *testing.T) {
 		require.Equal(t, payload.Message.Body.ExecutionPayloadHeader.BlockHash, resp.Deneb.ExecutionPayload.BlockHash)
 	})
 
+	t.Run("Deadline caps the request timeout", func(t *testing.T) {
+		backend := newBackend(t, 1, time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - config.SlotTimeSec
+		slotStart := time.Unix(int64(backend.boost.genesisTime+config.SlotTimeSec), 0)
+		backend.boost.getPayloadDeadline = time.Since(slotStart) + 100*time.Millisecond
+		backend.relays[0].ResponseDelay = 500 * time.Millisecond
+
+		start := time.Now()
+		rr := backend.request(t, http.MethodPost, path, payload)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+		require.Less(t, time.Since(start), 450*time.Millisecond)
+	})
+
+	t.Run("Deadline passed uses the request timeout", func(t *testing.T) {
+		backend := newBackend(t, 1, time.Second)
+		backend.boost.genesisTime = uint64(time.Now().Unix()) - 2*config.SlotTimeSec
+		backend.boost.getPayloadDeadline = time.Second
+		rr := backend.request(t, http.MethodPost, path, payload)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	})
+
 	t.Run("Bad response from relays", func(t *testing.T) {
-		backend := newTestBackend(t, 2, time.Second)
+		backend := newBackend(t, 2, time.Second)
 		resp := &builderApi.VersionedSubmitBlindedBlockResponse{
 			Version: spec.DataVersionDeneb,
 			Deneb: &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
@@ -661,7 +1092,7 @@ func TestGetPayload(t *testing.T) {
 		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
 
 		// 2/2 failing responses are okay
-		backend = newTestBackend(t, 2, time.Second)
+		backend = newBackend(t, 2, time.Second)
 		backend.relays[0].GetPayloadResponse = resp
 		backend.relays[1].GetPayloadResponse = resp
 		rr = backend.request(t, http.MethodPost, path, payload)
@@ -672,7 +1103,7 @@ func TestGetPayload(t *testing.T) {
 	})
 
 	t.Run("Retries on error from relay", func(t *testing.T) {
-		backend := newTestBackend(t, 1, 2*time.Second)
+		backend := newBackend(t, 1, 2*time.Second)
 
 		count := 0
 		backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
@@ -691,7 +1122,7 @@ func TestGetPayload(t *testing.T) {
 	})
 
 	t.Run("Error after max retries are reached", func(t *testing.T) {
-		backend := newTestBackend(t, 1, time.Second)
+		backend := newBackend(t, 1, time.Second)
 
 		count := 0
 		maxRetries := 5
@@ -717,7 +1148,7 @@ func TestGetPayload(t *testing.T) {
 func TestCheckRelays(t *testing.T) {
 	t.Run("One relay is okay", func(t *testing.T) {
 		backend := newTestBackend(t, 1, time.Second)
-		numHealthyRelays := backend.boost.CheckRelays()
+		numHealthyRelays := backend.boost.CheckRelays(context.Background())
 		require.Equal(t, 1, numHealthyRelays)
 	})
 
@@ -725,7 +1156,7 @@ func TestCheckRelays(t *testing.T) {
 		backend := newTestBackend(t, 1, time.Second)
 		backend.relays[0].Server.Close()
 
-		numHealthyRelays := backend.boost.CheckRelays()
+		numHealthyRelays := backend.boost.CheckRelays(context.Background())
 		require.Equal(t, 0, numHealthyRelays)
 	})
 
@@ -733,7 +1164,7 @@ func TestCheckRelays(t *testing.T) {
 		backend := newTestBackend(t, 2, time.Second)
 		backend.relays[0].Server.Close()
 
-		numHealthyRelays := backend.boost.CheckRelays()
+		numHealthyRelays := backend.boost.CheckRelays(context.Background())
 		require.Equal(t, 1, numHealthyRelays)
 	})
 
@@ -747,7 +1178,7 @@ func TestCheckRelays(t *testing.T) {
 		url, err := url.ParseRequestURI(backend.relays[0].Server.URL)
 		require.NoError(t, err)
 		backend.boost.relays[0].URL = url
-		numHealthyRelays := backend.boost.CheckRelays()
+		numHealthyRelays := backend.boost.CheckRelays(context.Background())
 		require.Equal(t, 0, numHealthyRelays)
 	})
 }
@@ -766,6 +1197,7 @@ func TestGetPayloadDeneb(t *testing.T) {
 	defer jsonFile.Close()
 	signedBlindedBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
 	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBlock)
 
 	backend := newTestBackend(t, 1, time.Second)
 
@@ -787,41 +1219,351 @@ func TestGetPayloadDeneb(t *testing.T) {
 	require.Equal(t, signedBlindedBlock.Message.Body.ExecutionPayloadHeader.BlockHash, resp.Deneb.ExecutionPayload.BlockHash)
 }
 
-func TestGetPayloadToAllRelays(t *testing.T) {
+func TestGetPayloadElectra(t *testing.T) {
 	// Load the signed blinded beacon block used for getPayload
-	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-electra.json")
 	require.NoError(t, err)
 	defer jsonFile.Close()
-	signedBlindedBeaconBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
-	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBeaconBlock))
+	signedBlindedBlock := new(eth2ApiV1Electra.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockElectra(t, signedBlindedBlock)
+	header := signedBlindedBlock.Message.Body.ExecutionPayloadHeader
 
-	// Create a test backend with 2 relays
-	backend := newTestBackend(t, 2, time.Second)
+	backend := newTestBackend(t, 1, time.Second)
+	backend.setSlot(uint64(signedBlindedBlock.Message.Slot))
+	schedule, err := types.NewForkSchedule([]types.Fork{
+		{Version: spec.DataVersionDeneb, Epoch: 0},
+		{Version: spec.DataVersionElectra, Epoch: uint64(signedBlindedBlock.Message.Slot) / types.SlotsPerEpoch},
+	})
+	require.NoError(t, err)
+	backend.boost.forkSchedule = schedule
 
-	// call getHeader, highest bid is returned by relay 0
-	getHeaderPath := "/eth/v1/builder/header/12345/0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2/0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	// call getHeader, so the bid and its execution requests are known
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
 	backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
 		12345,
-		"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
-		"0xcf8e0d4e9587369b2301d0790347320302cc0943d5a1884560367e8208d920f2",
-		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
-		spec.DataVersionDeneb,
+		header.BlockHash.String(),
+		header.ParentHash.String(),
+		pubkey.String(),
+		spec.DataVersionElectra,
 	)
+	getHeaderPath := getHeaderPath(uint64(signedBlindedBlock.Message.Slot), header.ParentHash, pubkey)
 	rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
 	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
-	require.Equal(t, 1, backend.relays[0].GetRequestCount(getHeaderPath))
-	require.Equal(t, 1, backend.relays[1].GetRequestCount(getHeaderPath))
 
-	// Prepare getPayload response
-	backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
-		Version: spec.DataVersionDeneb,
-		Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBeaconBlock),
+	// Prepare getPayload response, the relay expects the fork version header
+	response := &builderApi.VersionedSubmitBlindedBlockResponse{
+		Version: spec.DataVersionElectra,
+		Electra: blindedBlockContentsToPayloadElectra(signedBlindedBlock),
 	}
+	backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get(HeaderEthConsensusVersion) != spec.DataVersionElectra.String() {
+			http.Error(w, "unexpected consensus version", http.StatusBadRequest)
+			return
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	})
 
-	// call getPayload, ensure it's called to all relays
 	getPayloadPath := "/eth/v1/builder/blinded_blocks"
-	rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBeaconBlock)
-	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
-	require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
-	require.Equal(t, 1, backend.relays[1].GetRequestCount(getPayloadPath))
+	t.Run("Okay response from relay", func(t *testing.T) {
+		rr := backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+
+		resp := new(builderApi.VersionedSubmitBlindedBlockResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, spec.DataVersionElectra, resp.Version)
+		require.Equal(t, header.BlockHash, resp.Electra.ExecutionPayload.BlockHash)
+	})
+
+	t.Run("Execution requests do not match the bid", func(t *testing.T) {
+		block := *signedBlindedBlock
+		message := *block.Message
+		body := *message.Body
+		body.ExecutionRequests = &electra.ExecutionRequests{
+			Deposits:       []*electra.DepositRequest{},
+			Withdrawals:    []*electra.WithdrawalRequest{},
+			Consolidations: []*electra.ConsolidationRequest{{SourcePubkey: pubkey, TargetPubkey: pubkey}},
+		}
+		message.Body = &body
+		block.Message = &message
+
+		rr := backend.request(t, http.MethodPost, getPayloadPath, &block)
+		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+	})
+
+	t.Run("SSZ request and response", func(t *testing.T) {
+		body, err := signedBlindedBlock.MarshalSSZ()
+		require.NoError(t, err)
+		rr := backend.requestWithHeaders(t, http.MethodPost, getPayloadPath, body, map[string]string{
+			"Content-Type":            MediaTypeOctetStream,
+			"Accept":                  MediaTypeOctetStream,
+			HeaderEthConsensusVersion: "electra",
+		})
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, MediaTypeOctetStream, rr.Header().Get("Content-Type"))
+		require.Equal(t, "electra", rr.Header().Get(HeaderEthConsensusVersion))
+
+		resp := new(builderApiDeneb.ExecutionPayloadAndBlobsBundle)
+		require.NoError(t, resp.UnmarshalSSZ(rr.Body.Bytes()))
+		require.Equal(t, header.BlockHash, resp.ExecutionPayload.BlockHash)
+	})
+
+	t.Run("Unsupported request media type", func(t *testing.T) {
+		rr := backend.requestWithHeaders(t, http.MethodPost, getPayloadPath, []byte("{}"), map[string]string{"Content-Type": "text/plain"})
+		require.Equal(t, http.StatusUnsupportedMediaType, rr.Code, rr.Body.String())
+	})
+
+	t.Run("Response version does not match the request", func(t *testing.T) {
+		backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+			Version: spec.DataVersionDeneb,
+			Deneb:   blindedBlockContentsToPayloadElectra(signedBlindedBlock),
+		}
+		backend.relays[0].OverrideHandleGetPayload(func(w http.ResponseWriter, _ *http.Request) {
+			backend.relays[0].DefaultHandleGetPayload(w)
+		})
+		rr := backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+		require.Equal(t, http.StatusBadGateway, rr.Code, rr.Body.String())
+	})
+}
+
+func TestRelaySSZ(t *testing.T) {
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-electra.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBlock := new(eth2ApiV1Electra.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBlock))
+	sealBlindedBlockElectra(t, signedBlindedBlock)
+	header := signedBlindedBlock.Message.Body.ExecutionPayloadHeader
+	slot := uint64(signedBlindedBlock.Message.Slot)
+
+	pubkey := mock.HexToPubkey(
+		"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249")
+	getHeaderPath := getHeaderPath(slot, header.ParentHash, pubkey)
+	getPayloadPath := "/eth/v1/builder/blinded_blocks"
+
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 1, time.Second)
+		backend.setSlot(slot)
+		schedule, err := types.NewForkSchedule([]types.Fork{
+			{Version: spec.DataVersionDeneb, Epoch: 0},
+			{Version: spec.DataVersionElectra, Epoch: slot / types.SlotsPerEpoch},
+		})
+		require.NoError(t, err)
+		backend.boost.forkSchedule = schedule
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(12345, header.BlockHash.String(), header.ParentHash.String(), pubkey.String(), spec.DataVersionElectra)
+		backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+			Version: spec.DataVersionElectra,
+			Electra: blindedBlockContentsToPayloadElectra(signedBlindedBlock),
+		}
+		return backend
+	}
+
+	t.Run("SSZ getHeader and getPayload with a relay supporting SSZ", func(t *testing.T) {
+		backend := newBackend(t)
+		backend.relays[0].SupportsSSZ = true
+
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		supported, known := backend.boost.relaySSZ.get(backend.relays[0].RelayEntry)
+		require.True(t, known)
+		require.True(t, supported)
+
+		bid := new(builderSpec.VersionedSignedBuilderBid)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), bid))
+		require.Equal(t, spec.DataVersionElectra, bid.Version)
+		require.Equal(t, header.BlockHash, bid.Electra.Message.Header.BlockHash)
+
+		rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, MediaTypeOctetStream, backend.relays[0].GetRequestContentType(getPayloadPath))
+
+		resp := new(builderApi.VersionedSubmitBlindedBlockResponse)
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), resp))
+		require.Equal(t, spec.DataVersionElectra, resp.Version)
+		require.Equal(t, header.BlockHash, resp.Electra.ExecutionPayload.BlockHash)
+	})
+
+	t.Run("JSON getPayload to a relay not known to support SSZ", func(t *testing.T) {
+		backend := newBackend(t)
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		_, known := backend.boost.relaySSZ.get(backend.relays[0].RelayEntry)
+		require.False(t, known)
+
+		rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, MediaTypeJSON, backend.relays[0].GetRequestContentType(getPayloadPath))
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+	})
+
+	t.Run("Fallback to JSON on unsupported media type", func(t *testing.T) {
+		backend := newBackend(t)
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// The relay claimed SSZ support, but rejects SSZ request bodies
+		backend.boost.relaySSZ.set(backend.relays[0].RelayEntry, true)
+		rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 2, backend.relays[0].GetRequestCount(getPayloadPath))
+		require.Equal(t, MediaTypeJSON, backend.relays[0].GetRequestContentType(getPayloadPath))
+
+		supported, known := backend.boost.relaySSZ.get(backend.relays[0].RelayEntry)
+		require.True(t, known)
+		require.False(t, supported)
+	})
+
+	t.Run("Fallback to JSON on not acceptable", func(t *testing.T) {
+		backend := newBackend(t)
+		accepts := make([]string, 0)
+		backend.relays[0].OverrideHandleGetHeader(func(w http.ResponseWriter, req *http.Request) {
+			accepts = append(accepts, req.Header.Get("Accept"))
+			if strings.Contains(req.Header.Get("Accept"), MediaTypeOctetStream) {
+				http.Error(w, "not acceptable", http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Content-Type", MediaTypeJSON)
+			require.NoError(t, json.NewEncoder(w).Encode(backend.relays[0].GetHeaderResponse))
+		})
+
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, []string{acceptSSZ, ""}, accepts)
+
+		// SSZ is not requested again once the relay rejected it
+		rr = backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, []string{acceptSSZ, "", ""}, accepts)
+	})
+}
+
+func TestGetPayloadToBidRelays(t *testing.T) {
+	// Load the signed blinded beacon block used for getPayload
+	jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+	require.NoError(t, err)
+	defer jsonFile.Close()
+	signedBlindedBeaconBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+	require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBeaconBlock))
+	sealBlindedBlockDeneb(t, signedBlindedBeaconBlock)
+	slot := uint64(signedBlindedBeaconBlock.Message.Slot)
+	header := signedBlindedBeaconBlock.Message.Body.ExecutionPayloadHeader
+	pubkey := "0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"
+	headerPath := getHeaderPath(slot, header.ParentHash, mock.HexToPubkey(pubkey))
+	getPayloadPath := "/eth/v1/builder/blinded_blocks"
+
+	// Create a test backend with 2 relays, relay 0 being able to deliver the payload
+	newBackend := func(t *testing.T) *testBackend {
+		t.Helper()
+		backend := newTestBackend(t, 2, time.Second)
+		backend.setSlot(slot)
+		backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+			Version: spec.DataVersionDeneb,
+			Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBeaconBlock),
+		}
+		return backend
+	}
+
+	t.Run("Only to the relays which offered the bid", func(t *testing.T) {
+		backend := newBackend(t)
+
+		// call getHeader, only relay 0 bids for this block
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345, header.BlockHash.String(), header.ParentHash.String(), pubkey, spec.DataVersionDeneb)
+		rr := backend.request(t, http.MethodGet, headerPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(headerPath))
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(headerPath))
+
+		// call getPayload, ensure it's only called on relay 0
+		rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBeaconBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+		require.Equal(t, 0, backend.relays[1].GetRequestCount(getPayloadPath))
+	})
+
+	t.Run("Unknown bid, fall back to all relays", func(t *testing.T) {
+		backend := newBackend(t)
+		rr := backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBeaconBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(getPayloadPath))
+	})
+
+	t.Run("Unknown bid, no fallback", func(t *testing.T) {
+		backend := newBackend(t)
+		backend.boost.payloadFallback = PayloadFallbackNone
+		rr := backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBeaconBlock)
+		require.Equal(t, http.StatusBadRequest, rr.Code, rr.Body.String())
+		require.Contains(t, rr.Body.String(), errNoBidRelays.Error())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(getPayloadPath))
+		require.Equal(t, 0, backend.relays[1].GetRequestCount(getPayloadPath))
+	})
+}
+
+func TestUpdateSettings(t *testing.T) {
+	t.Run("Errors when no relays", func(t *testing.T) {
+		backend := newTestBackend(t, 1, time.Second)
+		err := backend.boost.UpdateSettings(RuntimeSettings{})
+		require.ErrorIs(t, err, errNoRelays)
+		require.Len(t, backend.boost.runtimeSettings().Relays, 1)
+	})
+
+	t.Run("getHeader uses the updated relays", func(t *testing.T) {
+		backend := newTestBackend(t, 2, time.Second)
+		settings := backend.boost.runtimeSettings()
+		settings.Relays = []types.RelayEntry{backend.relays[1].RelayEntry}
+		require.NoError(t, backend.boost.UpdateSettings(settings))
+
+		path := getHeaderPath(1, mock.HexToHash("0xe28385e7bd68df656cd0042b74b69c3104b5356ed1f20eb69f1f925df47a3ab7"), mock.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+		rr := backend.request(t, http.MethodGet, path, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 0, backend.relays[0].GetRequestCount(path))
+		require.Equal(t, 1, backend.relays[1].GetRequestCount(path))
+	})
+
+	t.Run("getPayload reaches removed relay which provided the bid", func(t *testing.T) {
+		jsonFile, err := os.Open("../testdata/signed-blinded-beacon-block-deneb.json")
+		require.NoError(t, err)
+		defer jsonFile.Close()
+		signedBlindedBeaconBlock := new(eth2ApiV1Deneb.SignedBlindedBeaconBlock)
+		require.NoError(t, DecodeJSON(jsonFile, &signedBlindedBeaconBlock))
+		sealBlindedBlockDeneb(t, signedBlindedBeaconBlock)
+
+		backend := newTestBackend(t, 2, time.Second)
+		backend.setSlot(uint64(signedBlindedBeaconBlock.Message.Slot))
+
+		// getHeader, the bid is only provided by relay 0
+		header := signedBlindedBeaconBlock.Message.Body.ExecutionPayloadHeader
+		getHeaderPath := getHeaderPath(uint64(signedBlindedBeaconBlock.Message.Slot), header.ParentHash, mock.HexToPubkey("0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249"))
+		backend.relays[0].GetHeaderResponse = backend.relays[0].MakeGetHeaderResponse(
+			12345,
+			header.BlockHash.String(),
+			header.ParentHash.String(),
+			"0x8a1d7b8dd64e0aafe7ea7b6c95065c9364cf99d38470c12ee807d55f7de1529ad29ce2c422e0b65e3d5a05c02caca249",
+			spec.DataVersionDeneb,
+		)
+		backend.relays[1].OverrideHandleGetHeader(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+		rr := backend.request(t, http.MethodGet, getHeaderPath, nil)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		// Remove relay 0 before getPayload
+		settings := backend.boost.runtimeSettings()
+		settings.Relays = []types.RelayEntry{backend.relays[1].RelayEntry}
+		require.NoError(t, backend.boost.UpdateSettings(settings))
+
+		backend.relays[0].GetPayloadResponse = &builderApi.VersionedSubmitBlindedBlockResponse{
+			Version: spec.DataVersionDeneb,
+			Deneb:   blindedBlockContentsToPayloadDeneb(signedBlindedBeaconBlock),
+		}
+		getPayloadPath := "/eth/v1/builder/blinded_blocks"
+		rr = backend.request(t, http.MethodPost, getPayloadPath, signedBlindedBeaconBlock)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		require.Equal(t, 1, backend.relays[0].GetRequestCount(getPayloadPath))
+	})
 }
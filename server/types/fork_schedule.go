@@ -0,0 +1,151 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/phase0"
+)
+
+// SlotsPerEpoch is the number of slots in an epoch
+const SlotsPerEpoch = 32
+
+var (
+	ErrUnknownFork       = errors.New("unknown fork")
+	ErrDuplicateFork     = errors.New("fork set more than once")
+	ErrForkEpochOrdering = errors.New("fork activates before the previous fork")
+)
+
+// Fork is a consensus-layer fork, activated at the start of an epoch
+type Fork struct {
+	Version     spec.DataVersion
+	ForkVersion phase0.Version
+	Epoch       uint64
+}
+
+// ForkSchedule is the ordered list of forks of a network, used to select the data version of a slot
+type ForkSchedule struct {
+	forks []Fork
+}
+
+// NewForkSchedule returns the fork schedule for the given forks, which may be passed in any order
+func NewForkSchedule(forks []Fork) (*ForkSchedule, error) {
+	sorted := make([]Fork, len(forks))
+	copy(sorted, forks)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, fork := range sorted {
+		if fork.Version == spec.DataVersionUnknown {
+			return nil, ErrUnknownFork
+		}
+		if i == 0 {
+			continue
+		}
+		if fork.Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateFork, fork.Version)
+		}
+		if fork.Epoch < sorted[i-1].Epoch {
+			return nil, fmt.Errorf("%w: %s", ErrForkEpochOrdering, fork.Version)
+		}
+	}
+	return &ForkSchedule{forks: sorted}, nil
+}
+
+// Forks returns the forks of the schedule, ordered by activation
+func (s *ForkSchedule) Forks() []Fork {
+	forks := make([]Fork, len(s.forks))
+	copy(forks, s.forks)
+	return forks
+}
+
+// ForkAtEpoch returns the latest fork activated at or before the epoch
+func (s *ForkSchedule) ForkAtEpoch(epoch uint64) (Fork, bool) {
+	for i := len(s.forks) - 1; i >= 0; i-- {
+		if s.forks[i].Epoch <= epoch {
+			return s.forks[i], true
+		}
+	}
+	return Fork{}, false
+}
+
+// ForkAtSlot returns the latest fork activated at or before the epoch of the slot
+func (s *ForkSchedule) ForkAtSlot(slot uint64) (Fork, bool) {
+	return s.ForkAtEpoch(slot / SlotsPerEpoch)
+}
+
+// VersionAtSlot returns the data version of the slot, or spec.DataVersionUnknown if no fork is active
+func (s *ForkSchedule) VersionAtSlot(slot uint64) spec.DataVersion {
+	fork, ok := s.ForkAtSlot(slot)
+	if !ok {
+		return spec.DataVersionUnknown
+	}
+	return fork.Version
+}
+
+// WithEpochs returns a copy of the schedule with the activation epochs of some forks replaced or added
+func (s *ForkSchedule) WithEpochs(epochs map[spec.DataVersion]uint64) (*ForkSchedule, error) {
+	forks := s.Forks()
+	for version, epoch := range epochs {
+		found := false
+		for i := range forks {
+			if forks[i].Version == version {
+				forks[i].Epoch = epoch
+				found = true
+			}
+		}
+		if !found {
+			forks = append(forks, Fork{Version: version, Epoch: epoch})
+		}
+	}
+	return NewForkSchedule(forks)
+}
+
+// ParseDataVersion returns the data version for a fork name such as "electra"
+func ParseDataVersion(name string) (spec.DataVersion, error) {
+	var version spec.DataVersion
+	if err := version.UnmarshalJSON([]byte(fmt.Sprintf("%q", strings.ToLower(name)))); err != nil {
+		return spec.DataVersionUnknown, fmt.Errorf("%w: %s", ErrUnknownFork, name)
+	}
+	return version, nil
+}
+
+func mustForkSchedule(forks []Fork) *ForkSchedule {
+	schedule, err := NewForkSchedule(forks)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// Fork schedules of the supported networks
+var (
+	ForkScheduleMainnet = mustForkSchedule([]Fork{
+		{Version: spec.DataVersionPhase0, ForkVersion: phase0.Version{0x00, 0x00, 0x00, 0x00}, Epoch: 0},
+		{Version: spec.DataVersionAltair, ForkVersion: phase0.Version{0x01, 0x00, 0x00, 0x00}, Epoch: 74240},
+		{Version: spec.DataVersionBellatrix, ForkVersion: phase0.Version{0x02, 0x00, 0x00, 0x00}, Epoch: 144896},
+		{Version: spec.DataVersionCapella, ForkVersion: phase0.Version{0x03, 0x00, 0x00, 0x00}, Epoch: 194048},
+		{Version: spec.DataVersionDeneb, ForkVersion: phase0.Version{0x04, 0x00, 0x00, 0x00}, Epoch: 269568},
+		{Version: spec.DataVersionElectra, ForkVersion: phase0.Version{0x05, 0x00, 0x00, 0x00}, Epoch: 364032},
+	})
+
+	ForkScheduleSepolia = mustForkSchedule([]Fork{
+		{Version: spec.DataVersionPhase0, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x69}, Epoch: 0},
+		{Version: spec.DataVersionAltair, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x70}, Epoch: 50},
+		{Version: spec.DataVersionBellatrix, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x71}, Epoch: 100},
+		{Version: spec.DataVersionCapella, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x72}, Epoch: 56832},
+		{Version: spec.DataVersionDeneb, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x73}, Epoch: 132608},
+		{Version: spec.DataVersionElectra, ForkVersion: phase0.Version{0x90, 0x00, 0x00, 0x74}, Epoch: 222464},
+	})
+
+	ForkScheduleHolesky = mustForkSchedule([]Fork{
+		{Version: spec.DataVersionPhase0, ForkVersion: phase0.Version{0x01, 0x01, 0x70, 0x00}, Epoch: 0},
+		{Version: spec.DataVersionAltair, ForkVersion: phase0.Version{0x02, 0x01, 0x70, 0x00}, Epoch: 0},
+		{Version: spec.DataVersionBellatrix, ForkVersion: phase0.Version{0x03, 0x01, 0x70, 0x00}, Epoch: 0},
+		{Version: spec.DataVersionCapella, ForkVersion: phase0.Version{0x04, 0x01, 0x70, 0x00}, Epoch: 256},
+		{Version: spec.DataVersionDeneb, ForkVersion: phase0.Version{0x05, 0x01, 0x70, 0x00}, Epoch: 29696},
+		{Version: spec.DataVersionElectra, ForkVersion: phase0.Version{0x06, 0x01, 0x70, 0x00}, Epoch: 115968},
+	})
+)
@@ -0,0 +1,57 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForkSchedule(t *testing.T) {
+	schedule, err := NewForkSchedule([]Fork{
+		{Version: spec.DataVersionElectra, Epoch: 20},
+		{Version: spec.DataVersionDeneb, Epoch: 10},
+	})
+	require.NoError(t, err)
+
+	_, ok := schedule.ForkAtSlot(10*SlotsPerEpoch - 1)
+	require.False(t, ok)
+	require.Equal(t, spec.DataVersionUnknown, schedule.VersionAtSlot(10*SlotsPerEpoch-1))
+	require.Equal(t, spec.DataVersionDeneb, schedule.VersionAtSlot(10*SlotsPerEpoch))
+	require.Equal(t, spec.DataVersionDeneb, schedule.VersionAtSlot(20*SlotsPerEpoch-1))
+	require.Equal(t, spec.DataVersionElectra, schedule.VersionAtSlot(20*SlotsPerEpoch))
+
+	forks := schedule.Forks()
+	require.Len(t, forks, 2)
+	require.Equal(t, spec.DataVersionDeneb, forks[0].Version)
+}
+
+func TestForkScheduleWithEpochs(t *testing.T) {
+	schedule, err := ForkScheduleMainnet.WithEpochs(map[spec.DataVersion]uint64{spec.DataVersionElectra: 400000})
+	require.NoError(t, err)
+	require.Equal(t, spec.DataVersionDeneb, schedule.VersionAtSlot(364032*SlotsPerEpoch))
+	require.Equal(t, spec.DataVersionElectra, schedule.VersionAtSlot(400000*SlotsPerEpoch))
+
+	// The preset is unchanged
+	require.Equal(t, spec.DataVersionElectra, ForkScheduleMainnet.VersionAtSlot(364032*SlotsPerEpoch))
+
+	_, err = ForkScheduleMainnet.WithEpochs(map[spec.DataVersion]uint64{spec.DataVersionElectra: 0})
+	require.ErrorIs(t, err, ErrForkEpochOrdering)
+}
+
+func TestNewForkScheduleErrors(t *testing.T) {
+	_, err := NewForkSchedule([]Fork{{Version: spec.DataVersionDeneb}, {Version: spec.DataVersionDeneb, Epoch: 1}})
+	require.ErrorIs(t, err, ErrDuplicateFork)
+
+	_, err = NewForkSchedule([]Fork{{Version: spec.DataVersionUnknown}})
+	require.ErrorIs(t, err, ErrUnknownFork)
+}
+
+func TestParseDataVersion(t *testing.T) {
+	version, err := ParseDataVersion("Electra")
+	require.NoError(t, err)
+	require.Equal(t, spec.DataVersionElectra, version)
+
+	_, err = ParseDataVersion("fulu2")
+	require.ErrorIs(t, err, ErrUnknownFork)
+}
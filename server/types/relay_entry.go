@@ -1,8 +1,10 @@
 package types
 
 import (
+	"math/big"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/go-boost-utils/utils"
@@ -12,12 +14,50 @@ import (
 type RelayEntry struct {
 	PublicKey phase0.BLSPubKey
 	URL       *url.URL
+
+	// Optional per-relay settings. Zero values mean the global setting applies.
+	Name              string
+	Headers           map[string]string
+	MinBid            *U256Str
+	TimeoutGetHeader  time.Duration
+	TimeoutGetPayload time.Duration
+	TimeoutRegVal     time.Duration
+	MaxRetries        int
+
+	// Optional adjustments of the bid values of the relay, used only to select the best bid. BidMultiplier is
+	// applied first (unset if 0), then BidAdjustment is added (in wei, may be negative).
+	BidMultiplier float64
+	BidAdjustment *big.Int
 }
 
 func (r *RelayEntry) String() string {
 	return r.URL.String()
 }
 
+// DisplayName returns the human-readable name of the relay if set, and the relay host otherwise.
+func (r *RelayEntry) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.URL.Host
+}
+
+// AdjustBid returns the value of a bid from the relay with the relay's multiplier and adjustment applied, to be
+// compared with the adjusted values of other bids. The bid itself is not changed.
+func (r *RelayEntry) AdjustBid(value *big.Int) *big.Int {
+	adjusted := new(big.Int).Set(value)
+	if r.BidMultiplier != 0 {
+		f := new(big.Float).SetInt(value)
+		f.Mul(f, big.NewFloat(r.BidMultiplier))
+		f.Add(f, big.NewFloat(0.5)) // round to the nearest wei, as multipliers like 0.95 are not exact
+		f.Int(adjusted)
+	}
+	if r.BidAdjustment != nil {
+		adjusted.Add(adjusted, r.BidAdjustment)
+	}
+	return adjusted
+}
+
 // GetURI returns the full request URI with scheme, host, path and args.
 func GetURI(url *url.URL, path string) string {
 	u2 := *url
@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"math/big"
 	"testing"
 
 	"github.com/flashbots/go-boost-utils/types"
@@ -109,3 +110,26 @@ func TestParseRelaysURLs(t *testing.T) {
 		})
 	}
 }
+
+func TestAdjustBid(t *testing.T) {
+	value := big.NewInt(1_000_000)
+	testCases := []struct {
+		name       string
+		multiplier float64
+		adjustment *big.Int
+		expected   *big.Int
+	}{
+		{name: "No adjustment", expected: big.NewInt(1_000_000)},
+		{name: "Multiplier", multiplier: 0.95, expected: big.NewInt(950_000)},
+		{name: "Negative adjustment", adjustment: big.NewInt(-10_000), expected: big.NewInt(990_000)},
+		{name: "Multiplier and adjustment", multiplier: 1.1, adjustment: big.NewInt(5_000), expected: big.NewInt(1_105_000)},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			relay := RelayEntry{BidMultiplier: tt.multiplier, BidAdjustment: tt.adjustment}
+			require.Equal(t, tt.expected, relay.AdjustBid(value))
+			require.Equal(t, big.NewInt(1_000_000), value)
+		})
+	}
+}
@@ -9,12 +9,19 @@ import (
 	"io"
 	"math/big"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	builderApi "github.com/attestantio/go-builder-client/api"
+	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
 	builderSpec "github.com/attestantio/go-builder-client/spec"
+	eth2Api "github.com/attestantio/go-eth2-client/api"
+	eth2ApiV1Electra "github.com/attestantio/go-eth2-client/api/v1/electra"
 	"github.com/attestantio/go-eth2-client/spec"
+	"github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
@@ -30,12 +37,24 @@ const (
 	HeaderKeySlotUID      = "X-MEVBoost-SlotID"
 	HeaderKeyVersion      = "X-MEVBoost-Version"
 	HeaderStartTimeUnixMS = "X-MEVBoost-StartTimeUnixMS"
+
+	HeaderEthConsensusVersion = "Eth-Consensus-Version"
+	HeaderTimeoutMs           = "X-Timeout-Ms"
+	HeaderDateMilliseconds    = "Date-Milliseconds"
 )
 
 var (
 	errHTTPErrorResponse  = errors.New("HTTP error response")
+	errDecodeResponse     = errors.New("could not unmarshal response")
 	errInvalidForkVersion = errors.New("invalid fork version")
 	errMaxRetriesExceeded = errors.New("max retries exceeded")
+	errDeadlineCutOff     = errors.New("request cut off by a deadline")
+
+	errUnsupportedVersion        = errors.New("unsupported consensus version")
+	errInvalidBid                = errors.New("invalid bid")
+	errInvalidBlindedBlock       = errors.New("invalid signed blinded beacon block")
+	errMissingExecutionRequests  = errors.New("missing execution requests")
+	errExecutionRequestsMismatch = errors.New("execution requests do not match the bid")
 )
 
 // UserAgent is a custom string type to avoid confusing url + userAgent parameters in SendHTTPRequest
@@ -51,14 +70,22 @@ func SendHTTPRequest(ctx context.Context, client http.Client, method, url string
 	if payload == nil {
 		req, err = http.NewRequestWithContext(ctx, method, url, nil)
 	} else {
-		payloadBytes, err2 := json.Marshal(payload)
+		contentType := MediaTypeJSON
+		var payloadBytes []byte
+		var err2 error
+		if sszRequest, ok := payload.(sszPayload); ok {
+			contentType = MediaTypeOctetStream
+			payloadBytes, err2 = sszRequest.MarshalSSZ()
+		} else {
+			payloadBytes, err2 = json.Marshal(payload)
+		}
 		if err2 != nil {
 			return 0, fmt.Errorf("could not marshal request: %w", err2)
 		}
 		req, err = http.NewRequestWithContext(ctx, method, url, bytes.NewReader(payloadBytes))
 
 		// Set headers
-		req.Header.Add("Content-Type", "application/json")
+		req.Header.Add("Content-Type", contentType)
 	}
 	if err != nil {
 		return 0, fmt.Errorf("could not prepare request: %w", err)
@@ -97,8 +124,16 @@ func SendHTTPRequest(ctx context.Context, client http.Client, method, url string
 			return resp.StatusCode, fmt.Errorf("could not read response body: %w", err)
 		}
 
+		// SSZ responses are decoded according to the version set in the response header
+		if decoder, ok := dst.(versionedResponse); ok && responseIsSSZ(resp) {
+			if err := decoder.unmarshalSSZ(resp.Header.Get(HeaderEthConsensusVersion), bodyBytes); err != nil {
+				return resp.StatusCode, fmt.Errorf("%w (ssz): %w", errDecodeResponse, err)
+			}
+			return resp.StatusCode, nil
+		}
+
 		if err := json.Unmarshal(bodyBytes, dst); err != nil {
-			return resp.StatusCode, fmt.Errorf("could not unmarshal response %s: %w", string(bodyBytes), err)
+			return resp.StatusCode, fmt.Errorf("%w %s: %w", errDecodeResponse, string(bodyBytes), err)
 		}
 	}
 
@@ -111,9 +146,9 @@ func SendHTTPRequestWithRetries(ctx context.Context, client http.Client, method,
 	var cancel context.CancelFunc
 	if client.Timeout > 0 {
 		// Create a context with a timeout as configured in the http client
-		requestCtx, cancel = context.WithTimeout(context.Background(), client.Timeout)
+		requestCtx, cancel = context.WithTimeout(ctx, client.Timeout)
 	} else {
-		requestCtx, cancel = context.WithCancel(context.Background())
+		requestCtx, cancel = context.WithCancel(ctx)
 	}
 	defer cancel()
 
@@ -128,9 +163,20 @@ func SendHTTPRequestWithRetries(ctx context.Context, client http.Client, method,
 		}
 
 		code, err = SendHTTPRequest(ctx, client, method, url, userAgent, headers, payload, dst)
+		if code == http.StatusNotAcceptable || code == http.StatusUnsupportedMediaType {
+			// Retrying with the same encoding cannot succeed
+			return code, err
+		}
 		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return code, err
+			}
 			log.WithError(err).Warn("error making request to relay, retrying")
-			time.Sleep(100 * time.Millisecond) // note: this timeout is only applied between retries, it does not delay the initial request!
+			// note: this timeout is only applied between retries, it does not delay the initial request!
+			select {
+			case <-requestCtx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
 			continue
 		}
 		return code, nil
@@ -178,6 +224,98 @@ type bidInfo struct {
 	blockNumber uint64
 	txRoot      phase0.Root
 	value       *uint256.Int
+	version     spec.DataVersion
+	extraData   []byte
+	gasLimit    uint64
+	header      bidHeaderFields
+}
+
+// bidHeaderFields are the execution payload header fields of a bid checked for plausibility
+type bidHeaderFields struct {
+	timestamp     uint64
+	baseFeePerGas *uint256.Int
+	gasUsed       uint64
+	blobGasUsed   uint64
+	numBlobs      int
+}
+
+// relayHTTPClient returns an HTTP client for relay requests, using the relay's timeout override if set
+func relayHTTPClient(timeout, relayTimeout time.Duration) http.Client {
+	if relayTimeout > 0 {
+		timeout = relayTimeout
+	}
+	return http.Client{
+		Timeout:       timeout,
+		CheckRedirect: httpClientDisallowRedirects,
+	}
+}
+
+// relayRequestHeaders returns the request headers including the relay's custom headers.
+// mev-boost headers take precedence over custom headers with the same key.
+func relayRequestHeaders(relay types.RelayEntry, headers map[string]string) map[string]string {
+	if len(relay.Headers) == 0 {
+		return headers
+	}
+	ret := make(map[string]string, len(relay.Headers)+len(headers))
+	for key, value := range relay.Headers {
+		ret[key] = value
+	}
+	for key, value := range headers {
+		ret[key] = value
+	}
+	return ret
+}
+
+// callerContext returns a context cancelled when the caller goes away, for requests which may still be in flight
+// once the handler has responded. Unlike the request context, it is not cancelled when the handler returns, as
+// long as done is called before.
+func callerContext(req *http.Request) (ctx context.Context, done func() bool) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+	return ctx, context.AfterFunc(req.Context(), cancel)
+}
+
+// beaconNodeDeadline returns the deadline of a beacon node request advertising its timeout with the X-Timeout-Ms
+// header, less the safety margin. The timeout counts from the Date-Milliseconds header if set and not in the future,
+// and from now otherwise. The zero time is returned if the request has no valid timeout.
+func beaconNodeDeadline(req *http.Request, now time.Time, margin time.Duration) time.Time {
+	timeoutMs, err := strconv.ParseUint(req.Header.Get(HeaderTimeoutMs), 10, 32)
+	if err != nil || timeoutMs == 0 {
+		return time.Time{}
+	}
+	start := now
+	if dateMs, err := strconv.ParseInt(req.Header.Get(HeaderDateMilliseconds), 10, 64); err == nil {
+		if date := time.UnixMilli(dateMs); date.Before(now) {
+			start = date
+		}
+	}
+	return start.Add(time.Duration(timeoutMs)*time.Millisecond - margin)
+}
+
+// earliestDeadline returns the earlier of two deadlines, ignoring unset ones
+func earliestDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// capTimeout lowers the client timeout to the time left until the deadline, if set and not passed yet
+func capTimeout(client *http.Client, deadline time.Time) {
+	if deadline.IsZero() {
+		return
+	}
+	if remaining := time.Until(deadline); remaining > 0 && (client.Timeout == 0 || remaining < client.Timeout) {
+		client.Timeout = remaining
+	}
+}
+
+// cutOffByDeadline marks a timeout as caused by the deadline the client timeout was capped to, rather than by the
+// relay being slow, once the deadline has passed
+func cutOffByDeadline(err error, deadline time.Time) error {
+	if err == nil || deadline.IsZero() || time.Now().Before(deadline) || classifyRelayError(err) != errorClassTimeout {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errDeadlineCutOff, err)
 }
 
 func httpClientDisallowRedirects(_ *http.Request, _ []*http.Request) error {
@@ -217,6 +355,17 @@ func parseBidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
 	if err != nil {
 		return bidInfo{}, err
 	}
+	if bid.Version == spec.DataVersionElectra && bid.Electra.Message.ExecutionRequests == nil {
+		return bidInfo{}, errMissingExecutionRequests
+	}
+	extraData, header, err := bidHeader(bid)
+	if err != nil {
+		return bidInfo{}, err
+	}
+	gasLimit, err := bid.BlockGasLimit()
+	if err != nil {
+		return bidInfo{}, err
+	}
 	bidInfo := bidInfo{
 		blockHash:   blockHash,
 		parentHash:  parentHash,
@@ -224,10 +373,57 @@ func parseBidInfo(bid *builderSpec.VersionedSignedBuilderBid) (bidInfo, error) {
 		blockNumber: blockNumber,
 		txRoot:      txRoot,
 		value:       value,
+		version:     bid.Version,
+		extraData:   extraData,
+		gasLimit:    gasLimit,
+		header:      header,
 	}
 	return bidInfo, nil
 }
 
+// bidHeader returns the extra data and the fields checked for plausibility of the execution payload header of a bid
+func bidHeader(bid *builderSpec.VersionedSignedBuilderBid) ([]byte, bidHeaderFields, error) {
+	switch bid.Version {
+	case spec.DataVersionCapella:
+		if bid.Capella == nil || bid.Capella.Message == nil || bid.Capella.Message.Header == nil {
+			return nil, bidHeaderFields{}, errInvalidBid
+		}
+		header := bid.Capella.Message.Header
+		var baseFeePerGas [32]byte // little-endian in capella headers
+		for i := range baseFeePerGas {
+			baseFeePerGas[i] = header.BaseFeePerGas[len(baseFeePerGas)-1-i]
+		}
+		return header.ExtraData, bidHeaderFields{
+			timestamp:     header.Timestamp,
+			baseFeePerGas: new(uint256.Int).SetBytes32(baseFeePerGas[:]),
+			gasUsed:       header.GasUsed,
+		}, nil
+	case spec.DataVersionDeneb:
+		if bid.Deneb == nil || bid.Deneb.Message == nil || bid.Deneb.Message.Header == nil {
+			return nil, bidHeaderFields{}, errInvalidBid
+		}
+		return bid.Deneb.Message.Header.ExtraData, denebHeaderFields(bid.Deneb.Message.Header, len(bid.Deneb.Message.BlobKZGCommitments)), nil
+	case spec.DataVersionElectra:
+		if bid.Electra == nil || bid.Electra.Message == nil || bid.Electra.Message.Header == nil {
+			return nil, bidHeaderFields{}, errInvalidBid
+		}
+		return bid.Electra.Message.Header.ExtraData, denebHeaderFields(bid.Electra.Message.Header, len(bid.Electra.Message.BlobKZGCommitments)), nil
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix:
+		return nil, bidHeaderFields{}, fmt.Errorf("%w: %s", errUnsupportedVersion, bid.Version)
+	}
+	return nil, bidHeaderFields{}, fmt.Errorf("%w: %s", errUnsupportedVersion, bid.Version)
+}
+
+func denebHeaderFields(header *deneb.ExecutionPayloadHeader, numBlobs int) bidHeaderFields {
+	return bidHeaderFields{
+		timestamp:     header.Timestamp,
+		baseFeePerGas: header.BaseFeePerGas,
+		gasUsed:       header.GasUsed,
+		blobGasUsed:   header.BlobGasUsed,
+		numBlobs:      numBlobs,
+	}
+}
+
 func checkRelaySignature(bid *builderSpec.VersionedSignedBuilderBid, domain phase0.Domain, pubKey phase0.BLSPubKey) (bool, error) {
 	root, err := bid.MessageHashTreeRoot()
 	if err != nil {
@@ -246,16 +442,84 @@ func checkRelaySignature(bid *builderSpec.VersionedSignedBuilderBid, domain phas
 	return bls.VerifySignatureBytes(msg[:], sig[:], pubKey[:])
 }
 
-func getPayloadResponseIsEmpty(payload *builderApi.VersionedSubmitBlindedBlockResponse) bool {
+// getPayloadResponseBundle returns the execution payload and blobs bundle of a getPayload response, or nil if
+// the version is not supported
+func getPayloadResponseBundle(payload *builderApi.VersionedSubmitBlindedBlockResponse) *builderApiDeneb.ExecutionPayloadAndBlobsBundle {
 	switch payload.Version {
 	case spec.DataVersionDeneb:
-		if payload.Deneb == nil || payload.Deneb.ExecutionPayload == nil ||
-			payload.Deneb.ExecutionPayload.BlockHash == nilHash ||
-			payload.Deneb.BlobsBundle == nil {
-			return true
+		return payload.Deneb
+	case spec.DataVersionElectra:
+		return payload.Electra
+	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
+		return nil
+	}
+	return nil
+}
+
+func getPayloadResponseIsEmpty(payload *builderApi.VersionedSubmitBlindedBlockResponse) bool {
+	bundle := getPayloadResponseBundle(payload)
+	return bundle == nil || bundle.ExecutionPayload == nil ||
+		bundle.ExecutionPayload.BlockHash == nilHash ||
+		bundle.BlobsBundle == nil
+}
+
+// blindedBlockRequest returns the version specific signed blinded beacon block to forward to the relays, along with
+// its execution payload header and blob KZG commitments
+func blindedBlockRequest(block *eth2Api.VersionedSignedBlindedBeaconBlock) (any, *deneb.ExecutionPayloadHeader, []deneb.KZGCommitment, error) {
+	switch block.Version {
+	case spec.DataVersionDeneb:
+		if block.Deneb == nil || block.Deneb.Message == nil || block.Deneb.Message.Body == nil || block.Deneb.Message.Body.ExecutionPayloadHeader == nil {
+			return nil, nil, nil, fmt.Errorf("%w: missing deneb block", errInvalidBlindedBlock)
 		}
+		return block.Deneb, block.Deneb.Message.Body.ExecutionPayloadHeader, block.Deneb.Message.Body.BlobKZGCommitments, nil
+	case spec.DataVersionElectra:
+		if block.Electra == nil || block.Electra.Message == nil || block.Electra.Message.Body == nil || block.Electra.Message.Body.ExecutionPayloadHeader == nil {
+			return nil, nil, nil, fmt.Errorf("%w: missing electra block", errInvalidBlindedBlock)
+		}
+		return block.Electra, block.Electra.Message.Body.ExecutionPayloadHeader, block.Electra.Message.Body.BlobKZGCommitments, nil
 	case spec.DataVersionUnknown, spec.DataVersionPhase0, spec.DataVersionAltair, spec.DataVersionBellatrix, spec.DataVersionCapella:
-		return true
+		return nil, nil, nil, fmt.Errorf("%w: %s", errUnsupportedVersion, block.Version)
+	}
+	return nil, nil, nil, fmt.Errorf("%w: %s", errUnsupportedVersion, block.Version)
+}
+
+// checkExecutionRequests verifies that the execution requests of an Electra blinded block are the ones of the bid
+func checkExecutionRequests(block *eth2ApiV1Electra.SignedBlindedBeaconBlock, bid *builderSpec.VersionedSignedBuilderBid) error {
+	if bid.Version != spec.DataVersionElectra || bid.Electra == nil || bid.Electra.Message == nil || bid.Electra.Message.ExecutionRequests == nil {
+		return errMissingExecutionRequests
+	}
+	blockRoot, err := block.Message.Body.ExecutionRequests.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	bidRoot, err := bid.Electra.Message.ExecutionRequests.HashTreeRoot()
+	if err != nil {
+		return err
+	}
+	if blockRoot != bidRoot {
+		return errExecutionRequestsMismatch
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a file through a temporary file in the same directory, so that a crash never leaves
+// a partial file behind
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
-	return false
+	return os.Rename(tmp.Name(), path)
 }
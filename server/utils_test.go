@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	builderApi "github.com/attestantio/go-builder-client/api"
 	builderApiDeneb "github.com/attestantio/go-builder-client/api/deneb"
@@ -16,6 +17,7 @@ import (
 	"github.com/attestantio/go-eth2-client/spec/deneb"
 	"github.com/attestantio/go-eth2-client/spec/phase0"
 	"github.com/flashbots/mev-boost/config"
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
 
@@ -89,6 +91,125 @@ func TestSendHTTPRequestGzip(t *testing.T) {
 	require.Equal(t, "test-message", resp.Msg)
 }
 
+func TestSendHTTPRequestSSZ(t *testing.T) {
+	bundle := &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
+		ExecutionPayload: &deneb.ExecutionPayload{BlockNumber: 12345, BaseFeePerGas: uint256.NewInt(0)},
+		BlobsBundle:      &builderApiDeneb.BlobsBundle{},
+	}
+	data, err := bundle.MarshalSSZ()
+	require.NoError(t, err)
+
+	newServer := func(consensusVersion string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, MediaTypeOctetStream, r.Header.Get("Content-Type"))
+			w.Header().Set("Content-Type", MediaTypeOctetStream)
+			w.Header().Set(HeaderEthConsensusVersion, consensusVersion)
+			_, _ = w.Write(data)
+		}))
+	}
+
+	t.Run("Decodes the version of the response header", func(t *testing.T) {
+		ts := newServer("electra")
+		defer ts.Close()
+		resp := &submitBlindedBlockResponse{VersionedSubmitBlindedBlockResponse: new(builderApi.VersionedSubmitBlindedBlockResponse)}
+		code, err := SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodPost, ts.URL, "", nil, sszPayload{bundle}, resp)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, code)
+		require.True(t, resp.isSSZ())
+		require.Equal(t, spec.DataVersionElectra, resp.Version)
+		require.Equal(t, uint64(12345), resp.Electra.ExecutionPayload.BlockNumber)
+	})
+
+	t.Run("Errors without a version header", func(t *testing.T) {
+		ts := newServer("")
+		defer ts.Close()
+		resp := &submitBlindedBlockResponse{VersionedSubmitBlindedBlockResponse: new(builderApi.VersionedSubmitBlindedBlockResponse)}
+		_, err := SendHTTPRequest(context.Background(), *http.DefaultClient, http.MethodPost, ts.URL, "", nil, sszPayload{bundle}, resp)
+		require.ErrorIs(t, err, errDecodeResponse)
+		require.ErrorIs(t, err, errUnsupportedVersion)
+	})
+}
+
+func TestEarliestDeadline(t *testing.T) {
+	now := time.Now()
+	later := now.Add(time.Second)
+	require.Equal(t, now, earliestDeadline(now, later))
+	require.Equal(t, now, earliestDeadline(later, now))
+	require.Equal(t, now, earliestDeadline(time.Time{}, now))
+	require.Equal(t, now, earliestDeadline(now, time.Time{}))
+	require.True(t, earliestDeadline(time.Time{}, time.Time{}).IsZero())
+}
+
+func TestBeaconNodeDeadline(t *testing.T) {
+	now := time.Now()
+	newRequest := func(headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		return req
+	}
+
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected time.Time
+	}{
+		{name: "No timeout", headers: map[string]string{}, expected: time.Time{}},
+		{name: "Invalid timeout", headers: map[string]string{HeaderTimeoutMs: "soon"}, expected: time.Time{}},
+		{name: "Timeout from now", headers: map[string]string{HeaderTimeoutMs: "1000"}, expected: now.Add(900 * time.Millisecond)},
+		{
+			name:     "Timeout from the request date",
+			headers:  map[string]string{HeaderTimeoutMs: "1000", HeaderDateMilliseconds: fmt.Sprint(now.Add(-200 * time.Millisecond).UnixMilli())},
+			expected: time.UnixMilli(now.Add(-200 * time.Millisecond).UnixMilli()).Add(900 * time.Millisecond),
+		},
+		{
+			name:     "Request date in the future",
+			headers:  map[string]string{HeaderTimeoutMs: "1000", HeaderDateMilliseconds: fmt.Sprint(now.Add(time.Second).UnixMilli())},
+			expected: now.Add(900 * time.Millisecond),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deadline := beaconNodeDeadline(newRequest(tc.headers), now, 100*time.Millisecond)
+			require.True(t, tc.expected.Equal(deadline), "expected %s, got %s", tc.expected, deadline)
+		})
+	}
+}
+
+func TestCallerContext(t *testing.T) {
+	t.Run("Cancelled when the caller goes away", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(context.Background())
+		ctx, done := callerContext(httptest.NewRequest(http.MethodPost, "/", nil).WithContext(reqCtx))
+		defer done()
+		cancel()
+		<-ctx.Done()
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("Not cancelled once done", func(t *testing.T) {
+		reqCtx, cancel := context.WithCancel(context.Background())
+		ctx, done := callerContext(httptest.NewRequest(http.MethodPost, "/", nil).WithContext(reqCtx))
+		done()
+		cancel()
+		time.Sleep(10 * time.Millisecond)
+		require.NoError(t, ctx.Err())
+	})
+}
+
+func TestCapTimeout(t *testing.T) {
+	client := http.Client{Timeout: time.Second}
+	capTimeout(&client, time.Time{})
+	require.Equal(t, time.Second, client.Timeout)
+
+	capTimeout(&client, time.Now().Add(-time.Second))
+	require.Equal(t, time.Second, client.Timeout)
+
+	capTimeout(&client, time.Now().Add(100*time.Millisecond))
+	require.LessOrEqual(t, client.Timeout, 100*time.Millisecond)
+	require.Positive(t, client.Timeout)
+}
+
 func TestWeiBigIntToEthBigFloat(t *testing.T) {
 	// test with valid input
 	i := big.NewInt(1)
@@ -166,6 +287,36 @@ func TestGetPayloadResponseIsEmpty(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "Non-empty electra payload response",
+			payload: &builderApi.VersionedSubmitBlindedBlockResponse{
+				Version: spec.DataVersionElectra,
+				Electra: &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
+					ExecutionPayload: &deneb.ExecutionPayload{
+						BlockHash: phase0.Hash32{0x1},
+					},
+					BlobsBundle: &builderApiDeneb.BlobsBundle{
+						Blobs:       make([]deneb.Blob, 0),
+						Commitments: make([]deneb.KZGCommitment, 0),
+						Proofs:      make([]deneb.KZGProof, 0),
+					},
+				},
+			},
+			expected: false,
+		},
+		{
+			name: "Electra payload response in deneb field",
+			payload: &builderApi.VersionedSubmitBlindedBlockResponse{
+				Version: spec.DataVersionElectra,
+				Deneb: &builderApiDeneb.ExecutionPayloadAndBlobsBundle{
+					ExecutionPayload: &deneb.ExecutionPayload{
+						BlockHash: phase0.Hash32{0x1},
+					},
+					BlobsBundle: &builderApiDeneb.BlobsBundle{},
+				},
+			},
+			expected: true,
+		},
 		{
 			name: "Unsupported payload version",
 			payload: &builderApi.VersionedSubmitBlindedBlockResponse{